@@ -0,0 +1,195 @@
+package xs4go
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+const (
+	defaultBulkBytes int = 4 << 20
+	maxBulkBytes     int = 32 << 20
+)
+
+var errBulkClosed = errors.New("bulk indexer is closed")
+
+// BulkOptions 批量索引选项
+type BulkOptions struct {
+	MaxBytes int           // 每批数据的最大字节数, 默认 4MB, 最大 32MB
+	MaxDocs  int           // 每批最多文档数, 0 表示不限制
+	Interval time.Duration // 定时提交的间隔, 0 表示不定时提交
+	// OnError 一批数据提交失败时调用, batch 为该批的数据, 可用于重试
+	OnError func(err error, batch *BulkBatch)
+}
+
+// BulkBatch 提交失败的一批数据
+//
+// Data 可以接在 XS_CMD_IMPORT_HEADER 之后以 ImportNative 格式重新导入
+type BulkBatch struct {
+	IDs  []string // 各文档的主键值, 原生格式导入的文档为空
+	Data []byte   // 该批编码后的索引指令
+}
+
+// BulkStats 批量索引统计
+type BulkStats struct {
+	Docs    uint64 // 已成功提交的文档数
+	Failed  uint64 // 提交失败的文档数
+	Batches uint64 // 已提交的批次数
+	Bytes   uint64 // 已成功提交的字节数
+	Elapsed time.Duration
+}
+
+// DocsPerSecond 平均每秒提交的文档数
+func (stats BulkStats) DocsPerSecond() float64 {
+	if stats.Elapsed <= 0 {
+		return 0
+	}
+	return float64(stats.Docs) / stats.Elapsed.Seconds()
+}
+
+// BulkIndexer 批量索引器, 将多个文档打包为 XS_CMD_INDEX_EXDATA 一次提交
+//
+// 文档数据达到 MaxBytes 或 MaxDocs, 或距上次提交超过 Interval 时自动提交.
+// 使用期间不要再直接调用所属 Indexer 的方法, 用完须调用 Close 提交剩余数据
+type BulkIndexer struct {
+	indexer *Indexer
+	opts    BulkOptions
+	mux     sync.Mutex
+	buf     bytes.Buffer
+	ids     []string
+	stats   BulkStats
+	start   time.Time
+	done    chan struct{}
+	closed  bool
+}
+
+// NewBulkIndexer creates a bulk indexer with opts
+func (indexer *Indexer) NewBulkIndexer(opts BulkOptions) *BulkIndexer {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultBulkBytes
+	} else if opts.MaxBytes > maxBulkBytes {
+		opts.MaxBytes = maxBulkBytes
+	}
+	bulk := &BulkIndexer{indexer: indexer, opts: opts, start: time.Now(), done: make(chan struct{})}
+	if opts.Interval > 0 {
+		go bulk.tick(opts.Interval)
+	}
+	return bulk
+}
+
+// Add 添加文档
+func (bulk *BulkIndexer) Add(doc map[string]string) error {
+	return bulk.append(schema.NewIndexDocument(doc), true)
+}
+
+// Update 更新文档
+func (bulk *BulkIndexer) Update(doc map[string]string) error {
+	return bulk.append(schema.NewIndexDocument(doc), false)
+}
+
+// AddDocument 添加带附加索引词或索引文本的文档
+func (bulk *BulkIndexer) AddDocument(doc *schema.Document) error {
+	return bulk.append(doc, true)
+}
+
+// UpdateDocument 更新带附加索引词或索引文本的文档
+func (bulk *BulkIndexer) UpdateDocument(doc *schema.Document) error {
+	return bulk.append(doc, false)
+}
+
+// Flush 立即提交缓冲的文档
+func (bulk *BulkIndexer) Flush() error {
+	bulk.mux.Lock()
+	defer bulk.mux.Unlock()
+	return bulk.flush()
+}
+
+// Close 提交剩余的文档并停止定时提交, 不会关闭所属的 Indexer
+func (bulk *BulkIndexer) Close() error {
+	bulk.mux.Lock()
+	defer bulk.mux.Unlock()
+	if bulk.closed {
+		return nil
+	}
+	bulk.closed = true
+	close(bulk.done)
+	return bulk.flush()
+}
+
+// Stats 返回统计信息
+func (bulk *BulkIndexer) Stats() BulkStats {
+	bulk.mux.Lock()
+	defer bulk.mux.Unlock()
+	stats := bulk.stats
+	stats.Elapsed = time.Since(bulk.start)
+	return stats
+}
+
+func (bulk *BulkIndexer) append(doc *schema.Document, add bool) error {
+	cmds, err := bulk.indexer.docCmds(doc, add)
+	if err != nil {
+		return err
+	}
+	return bulk.write(encodeCmds(cmds), doc.Fields[bulk.indexer.schema.StrId])
+}
+
+// write 写入已编码的指令, ids 为其中各文档的主键值
+func (bulk *BulkIndexer) write(data []byte, ids ...string) error {
+	bulk.mux.Lock()
+	defer bulk.mux.Unlock()
+	if bulk.closed {
+		return errBulkClosed
+	}
+	if bulk.buf.Len() > 0 && bulk.buf.Len()+len(data) > bulk.opts.MaxBytes {
+		if err := bulk.flush(); err != nil {
+			return err
+		}
+	}
+	bulk.buf.Write(data)
+	bulk.ids = append(bulk.ids, ids...)
+	if bulk.buf.Len() >= bulk.opts.MaxBytes || (bulk.opts.MaxDocs > 0 && len(bulk.ids) >= bulk.opts.MaxDocs) {
+		return bulk.flush()
+	}
+	return nil
+}
+
+// flush 提交缓冲区, 调用前须持有锁
+func (bulk *BulkIndexer) flush() error {
+	if bulk.buf.Len() == 0 {
+		return nil
+	}
+	size, ids := bulk.buf.Len(), bulk.ids
+	data := bulk.buf.String()
+	cmdx := cmd.NewCommand(cmd.XS_CMD_INDEX_EXDATA, 0, data)
+	bulk.buf.Reset()
+	bulk.ids = nil
+	bulk.stats.Batches++
+	if _, err := bulk.indexer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_RQST_FINISHED); err != nil {
+		bulk.stats.Failed += uint64(len(ids))
+		if bulk.opts.OnError != nil {
+			bulk.opts.OnError(err, &BulkBatch{IDs: ids, Data: []byte(data)})
+		}
+		return err
+	}
+	bulk.stats.Docs += uint64(len(ids))
+	bulk.stats.Bytes += uint64(size)
+	return nil
+}
+
+func (bulk *BulkIndexer) tick(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			// 错误已通过 OnError 报告
+			bulk.Flush()
+		case <-bulk.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// xs-indexer 索引管理工具, 功能与 xunsearch 的 Indexer.php 相当
+//
+//	xs-indexer -c demo.toml --clean --source data.csv
+//	xs-indexer -c demo.toml --rebuild --source data.jsonl
+//	xs-indexer -c demo.toml --del 1,2,3 --flush
+//	xs-indexer -c demo.toml --add-synonym "西湖:西子湖,sh" --info
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	xs "github.com/ninggf/xs4go"
+	"github.com/ninggf/xs4go/schema"
+)
+
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, " ")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+type options struct {
+	config      string
+	db          string
+	clean       bool
+	rebuild     bool
+	flush       bool
+	flushLog    bool
+	del         string
+	sources     stringList
+	format      string
+	delimiter   string
+	update      bool
+	ignore      bool
+	addSynonyms stringList
+	delSynonyms stringList
+	info        bool
+}
+
+func main() {
+	opts := &options{}
+	flags := flag.NewFlagSet("xs-indexer", flag.ExitOnError)
+	flags.StringVar(&opts.config, "c", "", "项目配置文件(toml)")
+	flags.StringVar(&opts.config, "config", "", "同 -c")
+	flags.StringVar(&opts.db, "db", "", "操作的数据库, 默认为 db")
+	flags.BoolVar(&opts.clean, "clean", false, "清空索引数据库")
+	flags.BoolVar(&opts.rebuild, "rebuild", false, "以 --source 的数据重建索引")
+	flags.BoolVar(&opts.flush, "flush", false, "强制刷新索引, 使之前提交的数据立即可搜索")
+	flags.BoolVar(&opts.flushLog, "flush-log", false, "强制刷新搜索日志")
+	flags.StringVar(&opts.del, "del", "", "删除主键为指定值的文档, 多个值以逗号分隔")
+	flags.Var(&opts.sources, "source", "导入数据文件, 可多次指定, - 表示标准输入")
+	flags.StringVar(&opts.format, "format", "", "数据格式: csv, jsonl 或 native, 默认按扩展名判断")
+	flags.StringVar(&opts.delimiter, "csv-delimiter", ",", "CSV 的分隔符")
+	flags.BoolVar(&opts.update, "update", false, "以更新方式导入数据(按主键替换)")
+	flags.BoolVar(&opts.ignore, "ignore-errors", false, "忽略数据有误的行, 否则有错误时以非零状态退出, 重建索引时放弃重建")
+	flags.Var(&opts.addSynonyms, "add-synonym", "添加同义词, 格式为 词:同义词1,同义词2, 可多次指定")
+	flags.Var(&opts.delSynonyms, "del-synonym", "删除同义词, 格式同 --add-synonym, 省略同义词时删除该词的全部同义词")
+	flags.BoolVar(&opts.info, "info", false, "显示项目和索引数据库信息")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: xs-indexer -c <config.toml> [options]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	if opts.config == "" && flags.NArg() > 0 {
+		opts.config = flags.Arg(0)
+	}
+	if opts.config == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts *options) error {
+	if opts.rebuild && len(opts.sources) == 0 {
+		return fmt.Errorf("--rebuild requires --source")
+	}
+	indexer, err := xs.NewIndexer(opts.config)
+	if err != nil {
+		return err
+	}
+	defer indexer.Close()
+	if opts.db != "" {
+		if err := indexer.SetDB(opts.db); err != nil {
+			return err
+		}
+	}
+	if opts.del != "" {
+		ids := splitList(opts.del)
+		if err := indexer.Del(ids...); err != nil {
+			return err
+		}
+		fmt.Printf("删除 %d 个文档\n", len(ids))
+	}
+	if opts.clean {
+		if err := indexer.Clean(); err != nil {
+			return err
+		}
+		fmt.Println("索引数据库已清空")
+	}
+	if len(opts.sources) > 0 {
+		if err := importSources(indexer, opts); err != nil {
+			return err
+		}
+	}
+	for _, value := range opts.addSynonyms {
+		word, synonyms, err := parseSynonym(value)
+		if err != nil {
+			return err
+		}
+		if len(synonyms) == 0 {
+			return fmt.Errorf("missing synonyms of '%s'", word)
+		}
+		if err := indexer.AddSynonym(word, synonyms...); err != nil {
+			return err
+		}
+		fmt.Printf("添加同义词 %s: %s\n", word, strings.Join(synonyms, ", "))
+	}
+	for _, value := range opts.delSynonyms {
+		word, synonyms, err := parseSynonym(value)
+		if err != nil {
+			return err
+		}
+		if err := indexer.DelSynonym(word, synonyms...); err != nil {
+			return err
+		}
+		fmt.Printf("删除同义词 %s\n", word)
+	}
+	if opts.flush {
+		if err := indexer.FlushIndex(); err != nil {
+			return err
+		}
+		fmt.Println("已刷新索引")
+	}
+	if opts.flushLog {
+		if err := indexer.FlushLogging(); err != nil {
+			return err
+		}
+		fmt.Println("已刷新搜索日志")
+	}
+	if opts.info {
+		return showInfo(indexer, opts)
+	}
+	return nil
+}
+
+func importSources(indexer *xs.Indexer, opts *options) (err error) {
+	if opts.rebuild {
+		if err := indexer.BeginRebuild(); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				indexer.StopRebuild()
+			} else {
+				err = indexer.EndRebuild()
+			}
+		}()
+	}
+	imOpts := &xs.ImportOptions{Update: opts.update}
+	if opts.delimiter != "" {
+		imOpts.Comma = []rune(opts.delimiter)[0]
+	}
+	failed := 0
+	for _, source := range opts.sources {
+		format := opts.format
+		if format == "" {
+			if format, err = detectFormat(source); err != nil {
+				return err
+			}
+		}
+		file := os.Stdin
+		if source != "-" {
+			if file, err = os.Open(source); err != nil {
+				return err
+			}
+		}
+		start := time.Now()
+		n, err := indexer.Import(file, format, imOpts)
+		if source != "-" {
+			file.Close()
+		}
+		rows, err := reportErrors(os.Stderr, source, err)
+		if err != nil {
+			return err
+		}
+		failed += rows
+		elapsed := time.Since(start)
+		fmt.Printf("%s: 导入 %d 个文档, 耗时 %s\n", source, n, elapsed.Round(time.Millisecond))
+	}
+	if failed > 0 && !opts.ignore {
+		return fmt.Errorf("%d rows failed to import, use --ignore-errors to skip them", failed)
+	}
+	return nil
+}
+
+// reportErrors 输出导入出错的行并返回其数量, 其它错误原样返回
+func reportErrors(w io.Writer, source string, err error) (int, error) {
+	if errs, ok := err.(xs.ImportErrors); ok {
+		for _, e := range errs {
+			fmt.Fprintf(w, "%s: %v\n", source, e)
+		}
+		return len(errs), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", source, err)
+	}
+	return 0, nil
+}
+
+func showInfo(indexer *xs.Indexer, opts *options) error {
+	setting, err := schema.LoadConf(opts.config)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("项目: %s\n", setting.Conf.Name)
+	fmt.Printf("索引服务: %s\n", setting.Conf.IndexServer)
+	fmt.Printf("搜索服务: %s\n", setting.Conf.SearchServer)
+	fmt.Printf("数据库: %s\n", strings.Join(indexer.ListDBs(), ", "))
+	if info, err := indexer.GetDB(); err == nil {
+		fmt.Printf("数据库信息: %s\n", info)
+	} else {
+		fmt.Printf("数据库信息: %v\n", err)
+	}
+	fields := indexer.Schema().FieldMetas
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return fields[names[i]].Vno < fields[names[j]].Vno
+	})
+	fmt.Println("字段:")
+	for _, name := range names {
+		f := fields[name]
+		fmt.Printf("  %-4d %-16s %-8s %s\n", f.Vno, name, f.Type, f.Index)
+	}
+	return nil
+}
+
+// parseSynonym 解析 词:同义词1,同义词2 格式的同义词
+func parseSynonym(value string) (string, []string, error) {
+	parts := strings.SplitN(value, ":", 2)
+	word := strings.TrimSpace(parts[0])
+	if word == "" {
+		return "", nil, fmt.Errorf("invalid synonym '%s'", value)
+	}
+	if len(parts) == 1 {
+		return word, nil, nil
+	}
+	return word, splitList(parts[1]), nil
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// detectFormat 根据扩展名判断数据格式
+func detectFormat(source string) (string, error) {
+	switch strings.ToLower(filepath.Ext(source)) {
+	case ".csv":
+		return xs.ImportCSV, nil
+	case ".jsonl", ".json", ".ndjson":
+		return xs.ImportJSONL, nil
+	case ".xs", ".dat":
+		return xs.ImportNative, nil
+	}
+	return "", fmt.Errorf("unknown format of '%s', please specify --format", source)
+}
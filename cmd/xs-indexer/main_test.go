@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	xs "github.com/ninggf/xs4go"
+)
+
+func Test_parseSynonym(t *testing.T) {
+	tests := []struct {
+		value    string
+		word     string
+		synonyms []string
+		fail     bool
+	}{
+		{"西湖:西子湖, sh,", "西湖", []string{"西子湖", "sh"}, false},
+		{"西湖", "西湖", nil, false},
+		{" :西子湖", "", nil, true},
+	}
+	for _, tt := range tests {
+		word, synonyms, err := parseSynonym(tt.value)
+		if (err != nil) != tt.fail {
+			t.Errorf("parseSynonym(%v) error = %v", tt.value, err)
+			continue
+		}
+		if word != tt.word || !reflect.DeepEqual(synonyms, tt.synonyms) {
+			t.Errorf("parseSynonym(%v) = %v, %v", tt.value, word, synonyms)
+		}
+	}
+}
+
+func Test_detectFormat(t *testing.T) {
+	for source, want := range map[string]string{"a.CSV": "csv", "b.jsonl": "jsonl", "c.xs": "native"} {
+		if got, err := detectFormat(source); err != nil || got != want {
+			t.Errorf("detectFormat(%v) = %v, %v", source, got, err)
+		}
+	}
+	if _, err := detectFormat("data.txt"); err == nil {
+		t.Error("unknown extension should fail")
+	}
+}
+
+func Test_reportErrors(t *testing.T) {
+	var buf bytes.Buffer
+	errs := xs.ImportErrors{{Line: 2, Err: errors.New("bad")}, {Line: 5, Err: errors.New("worse")}}
+	if n, err := reportErrors(&buf, "a.csv", errs); n != 2 || err != nil {
+		t.Errorf("reportErrors() = %v, %v", n, err)
+	}
+	if !strings.Contains(buf.String(), "a.csv: line 5: worse") {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+	if n, err := reportErrors(&buf, "a.csv", errors.New("broken")); n != 0 || err == nil {
+		t.Errorf("reportErrors() = %v, %v", n, err)
+	}
+}
@@ -0,0 +1,363 @@
+// xs-search 搜索调试工具, 功能与 xunsearch 的 Quest.php 相当
+//
+//	xs-search -c demo.toml 西湖
+//	xs-search -c demo.toml --limit 20 --sort=-price,+id --facets category 西湖
+//	xs-search -c demo.toml --related 5 --expand xih --json 西湖
+//	xs-search -c demo.toml --hot total
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	xs "github.com/ninggf/xs4go"
+	"github.com/ninggf/xs4go/schema"
+)
+
+type options struct {
+	config    string
+	query     string
+	db        string
+	limit     uint
+	offset    uint
+	sort      string
+	fuzzy     bool
+	synonym   bool
+	facets    string
+	highlight bool
+	correct   bool
+	related   uint
+	expand    string
+	hot       string
+	synonyms  bool
+	json      bool
+}
+
+// report 一次执行的全部输出
+type report struct {
+	Query     string              `json:"query,omitempty"`
+	Total     uint32              `json:"total"`
+	Elapsed   float64             `json:"elapsed_ms"`
+	Docs      []docReport         `json:"docs,omitempty"`
+	Facets    map[string]xs.Facet `json:"facets,omitempty"`
+	Corrected []string            `json:"corrected,omitempty"`
+	Related   []string            `json:"related,omitempty"`
+	Expanded  []string            `json:"expanded,omitempty"`
+	Hot       map[string]uint32   `json:"hot,omitempty"`
+	Synonyms  map[string][]string `json:"synonyms,omitempty"`
+	fields    []string
+	result    *xs.SearchResult
+}
+
+type docReport struct {
+	Docid   uint32            `json:"docid"`
+	Rank    uint32            `json:"rank"`
+	Percent int32             `json:"percent"`
+	Weight  float32           `json:"weight"`
+	Ccount  uint32            `json:"ccount,omitempty"`
+	Matched []string          `json:"matched,omitempty"`
+	Fields  map[string]string `json:"fields"`
+	// Highlighted 以 <em> 高亮搜索词后的字段值, 需指定 --highlight
+	Highlighted map[string]string `json:"highlighted,omitempty"`
+}
+
+func main() {
+	opts := &options{}
+	flags := flag.NewFlagSet("xs-search", flag.ExitOnError)
+	flags.StringVar(&opts.config, "c", "", "项目配置文件(toml)")
+	flags.StringVar(&opts.config, "config", "", "同 -c")
+	flags.StringVar(&opts.query, "q", "", "搜索语句, 也可作为最后的参数给出")
+	flags.StringVar(&opts.db, "db", "", "搜索的数据库, 多个以逗号分隔")
+	flags.UintVar(&opts.limit, "limit", 10, "返回结果数量")
+	flags.UintVar(&opts.offset, "offset", 0, "结果偏移量")
+	flags.StringVar(&opts.sort, "sort", "", "排序字段, 多个以逗号分隔, 前缀 + 为升序, - 或无前缀为降序")
+	flags.BoolVar(&opts.fuzzy, "fuzzy", false, "模糊搜索(词之间以 OR 连接)")
+	flags.BoolVar(&opts.synonym, "synonym", false, "开启自动同义词搜索")
+	flags.StringVar(&opts.facets, "facets", "", "分面统计的字段, 多个以逗号分隔")
+	flags.BoolVar(&opts.highlight, "highlight", false, "高亮显示字段中的搜索词")
+	flags.BoolVar(&opts.correct, "correct", false, "显示纠错建议")
+	flags.UintVar(&opts.related, "related", 0, "显示相关搜索词的数量")
+	flags.StringVar(&opts.expand, "expand", "", "显示以该前缀展开的搜索词")
+	flags.StringVar(&opts.hot, "hot", "", "显示热门搜索词: total, lastnum 或 currnum")
+	flags.BoolVar(&opts.synonyms, "synonyms", false, "显示搜索语句的同义词, 无搜索语句时列出全部同义词")
+	flags.BoolVar(&opts.json, "json", false, "以 JSON 格式输出")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: xs-search -c <config.toml> [options] [query]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	if opts.query == "" && flags.NArg() > 0 {
+		opts.query = strings.Join(flags.Args(), " ")
+	}
+	if opts.config == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := checkOptions(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		flags.Usage()
+		os.Exit(2)
+	}
+	rep, err := run(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if opts.json {
+		err = writeJSON(os.Stdout, rep)
+	} else {
+		err = writeText(os.Stdout, rep, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// checkOptions 检查数量的取值范围, 热门词、展开词和相关词最多只能获取 255 个
+func checkOptions(opts *options) error {
+	if (opts.hot != "" || opts.expand != "") && opts.limit > math.MaxUint8 {
+		return fmt.Errorf("--limit must be at most %d with --hot or --expand", math.MaxUint8)
+	}
+	if opts.related > math.MaxUint8 {
+		return fmt.Errorf("--related must be at most %d", math.MaxUint8)
+	}
+	return nil
+}
+
+func run(opts *options) (*report, error) {
+	setting, err := schema.LoadConf(opts.config)
+	if err != nil {
+		return nil, err
+	}
+	searcher, err := xs.NewSearcher(opts.config)
+	if err != nil {
+		return nil, err
+	}
+	defer searcher.Close()
+
+	rep := &report{Query: opts.query, fields: fieldNames(setting.Schema)}
+	if opts.db != "" {
+		dbs := splitList(opts.db)
+		if err := searcher.SetDB(dbs[0]); err != nil {
+			return nil, err
+		}
+		for _, db := range dbs[1:] {
+			if err := searcher.AddDB(db); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.hot != "" {
+		rep.Hot = searcher.GetHotQuery(opts.hot, uint8(opts.limit))
+	}
+	if opts.expand != "" {
+		rep.Expanded = searcher.GetExpandedQuery(opts.expand, uint8(opts.limit))
+	}
+	if opts.synonyms {
+		if opts.query == "" {
+			rep.Synonyms = searcher.GetAllSynonyms(uint32(opts.limit), uint32(opts.offset), false)
+		} else {
+			rep.Synonyms = map[string][]string{opts.query: searcher.GetSynonyms(opts.query)}
+		}
+	}
+	if opts.query == "" {
+		return rep, nil
+	}
+
+	searcher.Fuzzy(opts.fuzzy)
+	searcher.SetAutoSynonyms(opts.synonym)
+	if opts.sort != "" {
+		if err := setSort(searcher, opts.sort); err != nil {
+			return nil, err
+		}
+	}
+	if opts.facets != "" {
+		if err := searcher.SetFacets(splitList(opts.facets), false); err != nil {
+			return nil, err
+		}
+	}
+	if opts.correct {
+		searcher.SetAutoCorrect(1, false)
+	}
+	res, err := searcher.Limit(uint32(opts.limit), uint32(opts.offset)).Search(opts.query)
+	if err != nil {
+		return nil, err
+	}
+	rep.Elapsed = float64(res.Elapsed) / float64(time.Millisecond)
+	rep.result = res
+	rep.Total = res.Total
+	rep.Facets = res.Facets
+	for _, doc := range res.Docs {
+		dr := docReport{
+			Docid:   doc.Docid,
+			Rank:    doc.Rank,
+			Percent: doc.Percent,
+			Weight:  doc.Weight,
+			Ccount:  doc.Ccount,
+			Matched: doc.Matched,
+			Fields:  doc.Fields,
+		}
+		if opts.highlight {
+			dr.Highlighted = highlightFields(res, doc.Fields, nil)
+		}
+		rep.Docs = append(rep.Docs, dr)
+	}
+	if opts.correct {
+		rep.Corrected = res.Corrected
+		if len(rep.Corrected) == 0 {
+			rep.Corrected = searcher.GetCorrectedQuery(opts.query)
+		}
+	}
+	if opts.related > 0 {
+		rep.Related = searcher.GetRelatedQuery(opts.query, uint8(opts.related))
+	}
+	return rep, nil
+}
+
+// setSort 解析 --sort 参数, 如 -price,+id
+func setSort(searcher *xs.Searcher, value string) error {
+	fields := []xs.SortField{}
+	for _, item := range splitList(value) {
+		field := xs.SortField{Name: item}
+		if strings.HasPrefix(item, "+") {
+			field = xs.SortField{Name: item[1:], Asc: true}
+		} else if strings.HasPrefix(item, "-") {
+			field.Name = item[1:]
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 1 {
+		return searcher.SetSort(fields[0].Name, fields[0].Asc)
+	}
+	return searcher.SetMultiSort(fields, false)
+}
+
+func writeJSON(w io.Writer, rep *report) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(rep)
+}
+
+func writeText(w io.Writer, rep *report, opts *options) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if rep.result != nil {
+		fmt.Fprintf(tw, "搜索: %s\n", rep.Query)
+		fmt.Fprintf(tw, "共约 %d 条结果, 耗时 %.3fms", rep.Total, rep.Elapsed)
+		if rep.result.Substituted {
+			fmt.Fprintf(tw, ", 已替换为: %s", rep.result.Suggestion)
+		}
+		fmt.Fprintln(tw)
+		// 字段值可能含有终端颜色, 不放入 tabwriter 的列中, 字段名自行对齐
+		width := 0
+		for _, name := range rep.fields {
+			if len(name) > width {
+				width = len(name)
+			}
+		}
+		for i, doc := range rep.Docs {
+			fmt.Fprintf(tw, "\n#%d\tdocid: %d\tpercent: %d%%\tweight: %.4f\n", int(opts.offset)+i+1, doc.Docid, doc.Percent, doc.Weight)
+			fields := doc.Fields
+			if opts.highlight {
+				fields = highlightFields(rep.result, doc.Fields, &xs.HighlightOptions{Pre: "\x1b[31m", Post: "\x1b[0m"})
+			}
+			for _, name := range rep.fields {
+				if value, ok := fields[name]; ok {
+					fmt.Fprintf(tw, "  %-*s  %s\n", width+1, name+":", value)
+				}
+			}
+		}
+	}
+	if len(rep.Facets) > 0 {
+		fmt.Fprintln(tw, "\n分面统计:")
+		fields := make([]string, 0, len(rep.Facets))
+		for field := range rep.Facets {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			facet := rep.Facets[field]
+			values := make([]string, 0, len(facet))
+			for value := range facet {
+				values = append(values, value)
+			}
+			sort.Slice(values, func(i, j int) bool { return facet[values[i]] > facet[values[j]] })
+			for _, value := range values {
+				fmt.Fprintf(tw, "  %s\t%s\t%d\n", field, value, facet[value])
+			}
+		}
+	}
+	writeList(tw, "纠错建议", rep.Corrected)
+	writeList(tw, "相关搜索", rep.Related)
+	writeList(tw, "展开搜索词", rep.Expanded)
+	if len(rep.Hot) > 0 {
+		fmt.Fprintln(tw, "\n热门搜索:")
+		words := make([]string, 0, len(rep.Hot))
+		for word := range rep.Hot {
+			words = append(words, word)
+		}
+		sort.Slice(words, func(i, j int) bool { return rep.Hot[words[i]] > rep.Hot[words[j]] })
+		for _, word := range words {
+			fmt.Fprintf(tw, "  %s\t%d\n", word, rep.Hot[word])
+		}
+	}
+	if len(rep.Synonyms) > 0 {
+		fmt.Fprintln(tw, "\n同义词:")
+		words := make([]string, 0, len(rep.Synonyms))
+		for word := range rep.Synonyms {
+			words = append(words, word)
+		}
+		sort.Strings(words)
+		for _, word := range words {
+			fmt.Fprintf(tw, "  %s\t%s\n", word, strings.Join(rep.Synonyms[word], ", "))
+		}
+	}
+	return tw.Flush()
+}
+
+// highlightFields 以 result 的搜索词高亮各字段值, hl 为 nil 时使用 <em>
+func highlightFields(result *xs.SearchResult, fields map[string]string, hl *xs.HighlightOptions) map[string]string {
+	highlighted := make(map[string]string, len(fields))
+	for name, value := range fields {
+		highlighted[name] = result.Highlight(value, hl)
+	}
+	return highlighted
+}
+
+func writeList(w io.Writer, title string, list []string) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s: %s\n", title, strings.Join(list, ", "))
+}
+
+// fieldNames 按字段序号排列的字段名
+func fieldNames(sc *schema.Schema) []string {
+	names := make([]string, 0, len(sc.FieldMetas))
+	for name := range sc.FieldMetas {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return sc.FieldMetas[names[i]].Vno < sc.FieldMetas[names[j]].Vno
+	})
+	return names
+}
+
+func splitList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
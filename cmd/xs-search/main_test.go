@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	xs "github.com/ninggf/xs4go"
+)
+
+func Test_writeText(t *testing.T) {
+	rep := &report{
+		Hot:      map[string]uint32{"西湖": 3, "杭州": 9},
+		Synonyms: map[string][]string{"西湖": {"西子湖", "sh"}},
+		Expanded: []string{"xihu", "西湖"},
+	}
+	var buf bytes.Buffer
+	if err := writeText(&buf, rep, &options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Index(out, "杭州") > strings.Index(out, "  西湖") {
+		t.Errorf("hot queries should be sorted by count:\n%s", out)
+	}
+	for _, want := range []string{"西子湖, sh", "展开搜索词: xihu, 西湖"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output should contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func Test_writeJSON(t *testing.T) {
+	rep := &report{Query: "西湖", Total: 2, Docs: []docReport{{Docid: 1, Fields: map[string]string{"id": "1"}}}}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, rep); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["query"] != "西湖" || got["total"] != float64(2) || len(got["docs"].([]interface{})) != 1 {
+		t.Errorf("unexpected json %s", buf.String())
+	}
+	if _, ok := got["hot"]; ok {
+		t.Error("empty hot queries should be omitted")
+	}
+}
+
+func Test_checkOptions(t *testing.T) {
+	tests := []struct {
+		opts    options
+		wantErr bool
+	}{
+		{options{limit: 300}, false},
+		{options{limit: 255, hot: "total"}, false},
+		{options{limit: 300, hot: "total"}, true},
+		{options{limit: 300, expand: "xih"}, true},
+		{options{related: 256}, true},
+	}
+	for _, tt := range tests {
+		if err := checkOptions(&tt.opts); (err != nil) != tt.wantErr {
+			t.Errorf("checkOptions(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func Test_highlight(t *testing.T) {
+	res := &xs.SearchResult{Terms: []string{"西湖"}}
+	fields := map[string]string{"id": "1", "title": "杭州西湖"}
+	hl := highlightFields(res, fields, nil)
+	if hl["title"] != "杭州<em>西湖</em>" || hl["id"] != "1" {
+		t.Errorf("unexpected highlighted fields %v", hl)
+	}
+
+	rep := &report{
+		Query:  "西湖",
+		Docs:   []docReport{{Docid: 1, Fields: fields}},
+		fields: []string{"id", "title"},
+		result: res,
+	}
+	var buf bytes.Buffer
+	if err := writeText(&buf, rep, &options{highlight: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "  id:     1\n") || !strings.Contains(out, "  title:  杭州\x1b[31m西湖\x1b[0m\n") {
+		t.Errorf("unexpected output %q", out)
+	}
+	buf.Reset()
+	rep.Docs[0].Highlighted = hl
+	if err := writeJSON(&buf, rep); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"title": "杭州<em>西湖</em>"`) {
+		t.Errorf("unexpected json %s", buf.String())
+	}
+}
@@ -0,0 +1,78 @@
+package xs4go
+
+import "context"
+
+// 以下为支持 context.Context 的方法: 以 ctx 的截止时间作为连接的读写超时,
+// ctx 被取消时立即中断阻塞的读写. 若请求被中断, 连接会被关闭, 之后需重新创建 Searcher/Indexer
+
+// SearchContext is the same as Search with ctx
+func (searcher *Searcher) SearchContext(ctx context.Context, queries ...string) (*SearchResult, error) {
+	var res *SearchResult
+	err := searcher.conn.WithContext(ctx, func() (err error) {
+		res, err = searcher.Search(queries...)
+		return err
+	})
+	return res, err
+}
+
+// CountContext is the same as Count with ctx, but returns the error
+func (searcher *Searcher) CountContext(ctx context.Context, query string) (uint32, error) {
+	var cnt uint32
+	err := searcher.conn.WithContext(ctx, func() (err error) {
+		cnt, err = searcher.getTotal(query)
+		return err
+	})
+	return cnt, err
+}
+
+// GetQueryContext is the same as GetQuery with ctx
+func (searcher *Searcher) GetQueryContext(ctx context.Context, query string) (string, error) {
+	var q string
+	err := searcher.conn.WithContext(ctx, func() (err error) {
+		q, err = searcher.GetQuery(query)
+		return err
+	})
+	return q, err
+}
+
+// AddContext is the same as Add with ctx
+func (indexer *Indexer) AddContext(ctx context.Context, doc map[string]string) error {
+	return indexer.conn.WithContext(ctx, func() error {
+		return indexer.Add(doc)
+	})
+}
+
+// UpdateContext is the same as Update with ctx
+func (indexer *Indexer) UpdateContext(ctx context.Context, doc map[string]string) error {
+	return indexer.conn.WithContext(ctx, func() error {
+		return indexer.Update(doc)
+	})
+}
+
+// DelContext is the same as Del with ctx
+func (indexer *Indexer) DelContext(ctx context.Context, terms ...string) error {
+	return indexer.conn.WithContext(ctx, func() error {
+		return indexer.Del(terms...)
+	})
+}
+
+// SubmitContext is the same as Submit with ctx
+func (indexer *Indexer) SubmitContext(ctx context.Context) error {
+	return indexer.conn.WithContext(ctx, func() error {
+		return indexer.Submit()
+	})
+}
+
+// FlushIndexContext is the same as FlushIndex with ctx
+func (indexer *Indexer) FlushIndexContext(ctx context.Context) error {
+	return indexer.conn.WithContext(ctx, func() error {
+		return indexer.FlushIndex()
+	})
+}
+
+// FlushLoggingContext is the same as FlushLogging with ctx
+func (indexer *Indexer) FlushLoggingContext(ctx context.Context) error {
+	return indexer.conn.WithContext(ctx, func() error {
+		return indexer.FlushLogging()
+	})
+}
@@ -0,0 +1,54 @@
+package xs4go
+
+// SetAutoCorrect 设置自动纠错: 搜索结果总数少于 threshold 时获取纠错建议, 0 表示不纠错
+//
+// 默认为 0. rerun 为 true 时以最佳建议重新搜索, 若其结果更多则替换本次结果并将
+// SearchResult.Substituted 设为 true. 使用 SetQuery 设置的默认搜索语句时不会重新搜索,
+// 因为纠错建议只是搜索语句, 重新搜索会丢失 AddRange、AddWeight 等附加条件
+func (searcher *Searcher) SetAutoCorrect(threshold uint32, rerun bool) *Searcher {
+	searcher.correction = threshold
+	searcher.rerun = rerun
+	return searcher
+}
+
+// correct 为结果数量不足的搜索获取纠错建议, 并按需以最佳建议重新搜索,
+// explicit 表示本次搜索是否指定了搜索语句
+func (searcher *Searcher) correct(result *SearchResult, explicit bool) error {
+	if result.Total >= searcher.correction {
+		return nil
+	}
+	query := result.Query
+	if query == searcher.query {
+		query = searcher.cleanFieldQuery(query)
+	}
+	for _, q := range searcher.GetCorrectedQuery(query) {
+		if q != "" {
+			result.Corrected = append(result.Corrected, q)
+		}
+	}
+	if len(result.Corrected) == 0 {
+		return nil
+	}
+	result.Suggestion = result.Corrected[0]
+	if !searcher.rerun || !explicit {
+		return nil
+	}
+	// 不采用重新搜索的结果时恢复本次搜索的状态, 使 GetFacets、Terms 等仍对应原搜索语句
+	lastCount, lastHlQuery, hlTerms := searcher.lastCount, searcher.lastHlQuery, searcher.hlTerms
+	facets, terms := searcher.Facets, searcher.terms
+	res, err := searcher.search(result.Suggestion)
+	if err != nil {
+		return err
+	}
+	if res.Total <= result.Total {
+		searcher.lastCount, searcher.lastHlQuery, searcher.hlTerms = lastCount, lastHlQuery, hlTerms
+		searcher.Facets, searcher.terms = facets, terms
+		return nil
+	}
+	result.Docs = res.Docs
+	result.Total = res.Total
+	result.Facets = res.Facets
+	result.Terms = res.Terms
+	result.Substituted = true
+	return nil
+}
@@ -0,0 +1,85 @@
+package xs4go
+
+import (
+	"fmt"
+
+	"github.com/ninggf/xs4go/cmd"
+)
+
+// defaultDB 服务端默认的数据库名称
+const defaultDB string = "db"
+
+// GetDB 获取服务端当前搜索数据库的描述信息
+func (searcher *Searcher) GetDB() (string, error) {
+	cmdx := cmd.NewCommand(cmd.XS_CMD_SEARCH_GET_DB, 0)
+	res, err := searcher.conn.ExecOK(cmdx, cmd.XS_CMD_OK_DB_INFO)
+	if err != nil {
+		return "", err
+	}
+	return res.Buf, nil
+}
+
+// ListDBs 返回当前参与搜索的数据库, 第一个为 SetDB 设置的数据库, 其余按 AddDB 的顺序排列
+func (searcher *Searcher) ListDBs() []string {
+	db := searcher.curDB
+	if db == "" {
+		db = defaultDB
+	}
+	return append([]string{db}, searcher.curDBs...)
+}
+
+// RemoveDB 将数据库从搜索范围中移除, 不能移除唯一的数据库
+func (searcher *Searcher) RemoveDB(db string) error {
+	dbs := []string{}
+	for _, name := range searcher.ListDBs() {
+		if name != db {
+			dbs = append(dbs, name)
+		}
+	}
+	if len(dbs) == len(searcher.curDBs)+1 {
+		return fmt.Errorf("database '%s' is not being searched", db)
+	} else if len(dbs) == 0 {
+		return fmt.Errorf("can not remove the only database '%s'", db)
+	}
+	lastDB, lastDBs := searcher.lastDB, searcher.lastDBs
+	defer func() {
+		searcher.lastDB, searcher.lastDBs = lastDB, lastDBs
+	}()
+	if err := searcher.SetDB(dbs[0]); err != nil {
+		return err
+	}
+	for _, name := range dbs[1:] {
+		if err := searcher.AddDB(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docDB 根据 docid 计算文档所在的数据库
+//
+// 同时搜索 n 个数据库时, 第 i 个数据库中的文档 docid 为 (原docid - 1) * n + i + 1
+func docDB(dbs []string, docid uint32) string {
+	if len(dbs) == 0 || docid == 0 {
+		return ""
+	}
+	return dbs[(docid-1)%uint32(len(dbs))]
+}
+
+// GetDB 获取服务端当前索引数据库的描述信息
+func (indexer *Indexer) GetDB() (string, error) {
+	cmdx := cmd.NewCommand(cmd.XS_CMD_INDEX_GET_DB, 0)
+	res, err := indexer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_DB_INFO)
+	if err != nil {
+		return "", err
+	}
+	return res.Buf, nil
+}
+
+// ListDBs 返回当前写入的数据库, 索引时同一时刻只能写入一个数据库
+func (indexer *Indexer) ListDBs() []string {
+	if indexer.curDB == "" {
+		return []string{defaultDB}
+	}
+	return []string{indexer.curDB}
+}
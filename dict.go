@@ -0,0 +1,124 @@
+package xs4go
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ninggf/xs4go/cmd"
+)
+
+// DictEntry 自定义词典中的一个词条
+type DictEntry struct {
+	Word string
+	TF   float32 // 词频, 默认为 1
+	IDF  float32 // 逆文档频率, 默认为 1
+	Attr string  // 词性, 默认为 n
+}
+
+// String 返回 scws 文本词典格式的词条: 词\tTF\tIDF\t词性
+func (entry DictEntry) String() string {
+	tf, idf, attr := entry.TF, entry.IDF, entry.Attr
+	if tf <= 0 {
+		tf = 1
+	}
+	if idf <= 0 {
+		idf = 1
+	}
+	if attr == "" {
+		attr = "n"
+	}
+	return fmt.Sprintf("%s\t%g\t%g\t%s", entry.Word, tf, idf, attr)
+}
+
+// ParseDict 解析 scws 文本格式(txt)的词典
+//
+// 每行一个词条, 格式为 "词 TF IDF 词性", 以空白分隔, 除词外其余可省略; 以 # 或 ; 开头的行为注释
+func ParseDict(reader io.Reader) ([]DictEntry, error) {
+	return parseDict(reader, false)
+}
+
+// parseDict 解析词典, skip 为 true 时跳过无法解析的行, 否则返回错误
+func parseDict(reader io.Reader, skip bool) ([]DictEntry, error) {
+	entries := []DictEntry{}
+	scanner := bufio.NewScanner(reader)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' || text[0] == ';' {
+			continue
+		}
+		entry, err := parseDictEntry(text)
+		if err != nil {
+			if skip {
+				continue
+			}
+			return entries, fmt.Errorf("line %d: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func parseDictEntry(text string) (DictEntry, error) {
+	parts := strings.Fields(text)
+	entry := DictEntry{Word: parts[0], TF: 1, IDF: 1, Attr: "n"}
+	if len(parts) > 1 {
+		tf, err := strconv.ParseFloat(parts[1], 32)
+		if err != nil {
+			return entry, fmt.Errorf("invalid TF '%s'", parts[1])
+		}
+		entry.TF = float32(tf)
+	}
+	if len(parts) > 2 {
+		idf, err := strconv.ParseFloat(parts[2], 32)
+		if err != nil {
+			return entry, fmt.Errorf("invalid IDF '%s'", parts[2])
+		}
+		entry.IDF = float32(idf)
+	}
+	if len(parts) > 3 {
+		entry.Attr = parts[3]
+	}
+	return entry, nil
+}
+
+// LoadDict 从 scws 文本格式(txt)的词典文件中加载词条
+func LoadDict(file string) ([]DictEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseDict(f)
+}
+
+// GetCustomDict 获取当前项目在服务端的自定义词典, 无法解析的行会被跳过
+func (indexer *Indexer) GetCustomDict() ([]DictEntry, error) {
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_INDEX_USER_DICT, 0, 0)
+	res, err := indexer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_INFO)
+	if err != nil {
+		return nil, err
+	}
+	return parseDict(strings.NewReader(res.Buf), true)
+}
+
+// SetCustomDict 用 entries 替换当前项目在服务端的自定义词典, entries 为空时清空词典
+func (indexer *Indexer) SetCustomDict(entries []DictEntry) error {
+	buf := bytes.NewBufferString("")
+	for _, entry := range entries {
+		if entry.Word == "" {
+			continue
+		}
+		buf.WriteString(entry.String())
+		buf.WriteByte('\n')
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_INDEX_USER_DICT, 1, 0, buf.String())
+	_, err := indexer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_DICT_SAVED)
+	return err
+}
@@ -0,0 +1,90 @@
+package xs4go
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+// earthRadius 地球平均半径(米)
+const earthRadius float64 = 6371000
+
+type geoSort struct {
+	latField string
+	lonField string
+	lat      float64
+	lon      float64
+	radius   float64
+}
+
+// SetGeodistSort 按地理位置距离排序, 离 (lat, lon) 越近越靠前
+//
+// latField, lonField 分别为保存纬度、经度的字段, 必须在配置中声明为 numeric 类型.
+// 设置后搜索结果文档的 Distance 将被填充为到该点的距离(米)
+func (searcher *Searcher) SetGeodistSort(latField, lonField string, lat, lon float64) error {
+	buf := bytes.NewBuffer([]byte{})
+	// 服务端要求先经度后纬度
+	for _, gf := range []struct {
+		name  string
+		value float64
+	}{{lonField, lon}, {latField, lat}} {
+		f, err := searcher.getField(gf.name)
+		if err != nil {
+			return err
+		}
+		if !f.IsNumeric() {
+			return fmt.Errorf("type of geo field '%s' should be numeric", gf.name)
+		}
+		value := strconv.FormatFloat(gf.value, 'f', -1, 64)
+		buf.WriteByte(f.Vno)
+		buf.WriteByte(uint8(len(value)))
+		buf.WriteString(value)
+	}
+	sortType := uint8(cmd.XS_CMD_SORT_TYPE_GEODIST | cmd.XS_CMD_SORT_FLAG_ASCENDING)
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_SORT, sortType, 0, buf.String())
+	if _, err := searcher.conn.ExecOK(cmdx, 0); err != nil {
+		return err
+	}
+	searcher.geo = &geoSort{latField: latField, lonField: lonField, lat: lat, lon: lon}
+	return nil
+}
+
+// SetGeoRadius 只保留距离不超过 radius 米的搜索结果, 0 表示不限制
+//
+// 该过滤在客户端完成, 需先调用 SetGeodistSort, 且不影响 GetLastCount 返回的匹配总数
+func (searcher *Searcher) SetGeoRadius(radius float64) error {
+	if searcher.geo == nil {
+		return fmt.Errorf("geo sort is not set, please call SetGeodistSort first")
+	}
+	searcher.geo.radius = radius
+	return nil
+}
+
+// Geodist 计算两个经纬度坐标之间的球面距离(米)
+func Geodist(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// accept 计算文档的距离, 并判断是否在半径之内
+func (geo *geoSort) accept(doc *schema.Document) bool {
+	doc.Distance = -1
+	lat, err := strconv.ParseFloat(doc.Fields[geo.latField], 64)
+	if err != nil {
+		return geo.radius <= 0
+	}
+	lon, err := strconv.ParseFloat(doc.Fields[geo.lonField], 64)
+	if err != nil {
+		return geo.radius <= 0
+	}
+	doc.Distance = Geodist(geo.lat, geo.lon, lat, lon)
+	return geo.radius <= 0 || doc.Distance <= geo.radius
+}
@@ -0,0 +1,104 @@
+package xs4go
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// HighlightOptions 搜索结果高亮选项
+type HighlightOptions struct {
+	Pre    string   // 高亮词前缀, 与 Post 都为空时默认为 <em>
+	Post   string   // 高亮词后缀, 与 Pre 都为空时默认为 </em>
+	Escape bool     // 是否对原文做 HTML 转义, Pre 和 Post 不会被转义
+	Terms  []string // 需要高亮的词, 为空时使用最近一次搜索语句的高亮词(参见 Terms)
+}
+
+// Highlight 高亮显示文本中最近一次搜索语句所匹配的词
+//
+// opts 为 nil 时使用默认选项. 可将 Document.Matched 设为 opts.Terms 以只高亮该文档实际匹配的词
+func (searcher *Searcher) Highlight(text string, opts *HighlightOptions) string {
+	if opts == nil {
+		opts = &HighlightOptions{}
+	}
+	terms := opts.Terms
+	if len(terms) == 0 {
+		// 高亮词在下一次搜索或修改搜索语句前保持不变, 缓存以免每次高亮都请求服务端
+		if searcher.hlTerms == nil {
+			searcher.hlTerms = searcher.Terms(searcher.lastHlQuery)
+		}
+		terms = searcher.hlTerms
+	}
+	return HighlightTerms(text, terms, opts)
+}
+
+// HighlightTerms 用 opts.Pre 和 opts.Post 包裹 text 中出现的 terms
+//
+// 按字符(而非字节)匹配且忽略大小写, 相互重叠或相邻的匹配会被合并为一段高亮
+func HighlightTerms(text string, terms []string, opts *HighlightOptions) string {
+	if opts == nil {
+		opts = &HighlightOptions{}
+	}
+	pre, post := opts.Pre, opts.Post
+	if pre == "" && post == "" {
+		pre, post = "<em>", "</em>"
+	}
+	escape := func(s string) string {
+		if opts.Escape {
+			return html.EscapeString(s)
+		}
+		return s
+	}
+	if text == "" || len(terms) == 0 {
+		return escape(text)
+	}
+
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(runes))
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if runesEqual(lower[i:i+len(t)], t) {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+			}
+		}
+	}
+
+	buf := bytes.NewBufferString("")
+	start := 0
+	for start < len(runes) {
+		end := start
+		for end < len(runes) && marked[end] == marked[start] {
+			end++
+		}
+		segment := escape(string(runes[start:end]))
+		if marked[start] {
+			buf.WriteString(pre)
+			buf.WriteString(segment)
+			buf.WriteString(post)
+		} else {
+			buf.WriteString(segment)
+		}
+		start = end
+	}
+	return buf.String()
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,360 @@
+package xs4go
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+// 导入数据的格式
+const (
+	// ImportNative 以 XS_CMD_IMPORT_HEADER 开头, 之后为编码后的索引指令, 不支持 Update 和 Mapping 选项
+	ImportNative string = "native"
+	// ImportJSONL 每行一个 JSON 对象
+	ImportJSONL string = "jsonl"
+	// ImportCSV 第一行为表头的 CSV
+	ImportCSV string = "csv"
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Update  bool              // 以 Update 代替 Add 导入
+	Mapping map[string]string // JSONL 的键或 CSV 的表头到字段名的映射, 映射为 "-" 时忽略该列
+	Comma   rune              // CSV 的分隔符, 默认为逗号
+	Bulk    BulkOptions       // 提交数据的批量选项
+}
+
+// ImportError 某一行数据的导入错误
+//
+// JSONL 和 CSV 中 Line 为行号(跨行的 CSV 记录为其起始行), 原生格式中为文档的序号
+type ImportError struct {
+	Line int
+	Err  error
+}
+
+func (err *ImportError) Error() string {
+	return fmt.Sprintf("line %d: %v", err.Line, err.Err)
+}
+
+// ImportErrors 导入过程中出错的全部行
+type ImportErrors []*ImportError
+
+func (errs ImportErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", errs[0].Error(), len(errs)-1)
+}
+
+// Import 从 r 读取 format 格式的数据并批量导入索引, 返回成功提交的文档数
+//
+// 数据不合法的行会被跳过并继续导入, 全部完成后以 ImportErrors 返回;
+// 读取失败或服务端返回错误时立即中止. opts 可以为 nil
+func (indexer *Indexer) Import(r io.Reader, format string, opts *ImportOptions) (int, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+	if format == ImportNative && (opts.Update || len(opts.Mapping) > 0) {
+		return 0, fmt.Errorf("options Update and Mapping are not supported by native import")
+	}
+	im := &importer{indexer: indexer, opts: opts, bulk: indexer.NewBulkIndexer(opts.Bulk)}
+	var err error
+	switch format {
+	case ImportNative:
+		err = im.native(r)
+	case ImportJSONL:
+		err = im.jsonl(r)
+	case ImportCSV:
+		err = im.csv(r)
+	default:
+		err = fmt.Errorf("unsupported import format '%s'", format)
+	}
+	if cerr := im.bulk.Close(); err == nil {
+		err = cerr
+	}
+	docs := int(im.bulk.Stats().Docs)
+	if err == nil && len(im.errs) > 0 {
+		err = im.errs
+	}
+	return docs, err
+}
+
+type importer struct {
+	indexer *Indexer
+	opts    *ImportOptions
+	bulk    *BulkIndexer
+	errs    ImportErrors
+}
+
+// add 提交一行数据, 数据错误记录后返回 nil, 提交失败返回错误
+func (im *importer) add(line int, fields map[string]string) error {
+	doc := schema.NewIndexDocument(fields)
+	cmds, err := im.indexer.docCmds(doc, !im.opts.Update)
+	if err != nil {
+		im.errs = append(im.errs, &ImportError{line, err})
+		return nil
+	}
+	return im.bulk.write(encodeCmds(cmds), doc.Fields[im.indexer.schema.StrId])
+}
+
+// field 返回键对应的字段名, 忽略的键返回空字符串
+func (im *importer) field(key string) (string, error) {
+	name := key
+	if f, ok := im.opts.Mapping[key]; ok {
+		name = f
+	}
+	if name == "-" {
+		return "", nil
+	}
+	if _, ok := im.indexer.schema.FieldMetas[name]; !ok {
+		return "", fmt.Errorf("field '%s' is not defined", name)
+	}
+	return name, nil
+}
+
+func (im *importer) jsonl(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxBulkBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		fields, err := im.jsonFields(text)
+		if err != nil {
+			im.errs = append(im.errs, &ImportError{line, err})
+			continue
+		}
+		if err := im.add(line, fields); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (im *importer) jsonFields(text []byte) (map[string]string, error) {
+	values := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	for key, value := range values {
+		name, err := im.field(key)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" || value == nil {
+			continue
+		}
+		v, err := jsonValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("value of '%s': %v", key, err)
+		}
+		fields[name] = v
+	}
+	return fields, nil
+}
+
+// jsonValue 将 JSON 值转换为字段值, 数组的元素以空格连接
+func jsonValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if item == nil {
+				continue
+			}
+			s, err := jsonValue(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, " "), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+func (im *importer) csv(r io.Reader) error {
+	lines := &csvLines{reader: bufio.NewReader(r)}
+	header, line, err := im.csvRecord(lines)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	names := make([]string, len(header))
+	for i, key := range header {
+		if names[i], err = im.field(strings.TrimSpace(key)); err != nil {
+			return &ImportError{line, err}
+		}
+	}
+	for {
+		record, line, err := im.csvRecord(lines)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			if ierr, ok := err.(*ImportError); ok {
+				im.errs = append(im.errs, ierr)
+				continue
+			}
+			return err
+		}
+		if len(record) != len(names) {
+			im.errs = append(im.errs, &ImportError{line, fmt.Errorf("wrong number of columns %d, want %d", len(record), len(names))})
+			continue
+		}
+		fields := make(map[string]string)
+		for i, value := range record {
+			if names[i] != "" {
+				fields[names[i]] = value
+			}
+		}
+		if err := im.add(line, fields); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRecord 读取下一条非空记录及其起始行号, 记录格式错误时返回 *ImportError
+func (im *importer) csvRecord(lines *csvLines) ([]string, int, error) {
+	for {
+		text, line, err := lines.next()
+		if err != nil {
+			return nil, line, err
+		}
+		reader := csv.NewReader(strings.NewReader(text))
+		if im.opts.Comma != 0 {
+			reader.Comma = im.opts.Comma
+		}
+		reader.FieldsPerRecord = -1
+		record, err := reader.Read()
+		if err == io.EOF {
+			// 空行
+			continue
+		} else if perr, ok := err.(*csv.ParseError); ok {
+			return nil, line, &ImportError{line + perr.Line - 1, perr.Err}
+		} else if err != nil {
+			return nil, line, err
+		}
+		return record, line, nil
+	}
+}
+
+// csvLines 按物理行读取 CSV, 引号未闭合时继续读取下一行, 以得到每条记录起始的行号
+type csvLines struct {
+	reader *bufio.Reader
+	line   int
+}
+
+// next 返回一条记录的原文及其起始行号
+func (lines *csvLines) next() (string, int, error) {
+	buf := bytes.NewBufferString("")
+	start := lines.line + 1
+	quotes := 0
+	for {
+		text, err := lines.reader.ReadString('\n')
+		if text != "" {
+			lines.line++
+			buf.WriteString(text)
+			quotes += strings.Count(text, "\"")
+		}
+		if err == io.EOF && buf.Len() > 0 {
+			return buf.String(), start, nil
+		} else if err != nil {
+			return "", start, err
+		}
+		// 转义的引号成对出现, 引号总数为偶数时记录已结束
+		if quotes%2 == 0 {
+			return buf.String(), start, nil
+		}
+	}
+}
+
+// native 读取原生格式的数据, 文档以 XS_CMD_INDEX_REQUEST 开始、XS_CMD_INDEX_SUBMIT 结束,
+// 文档之外的指令(如 XS_CMD_INDEX_REMOVE)单独提交
+func (im *importer) native(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	head, _, err := readFrame(reader)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if head != cmd.XS_CMD_IMPORT_HEADER {
+		return fmt.Errorf("invalid import data, missing header")
+	}
+	doc := bytes.NewBuffer([]byte{})
+	docs := 0
+	for {
+		command, frame, err := readFrame(reader)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return &ImportError{docs + 1, err}
+		}
+		if doc.Len() == 0 && command != cmd.XS_CMD_INDEX_REQUEST {
+			if err := im.bulk.write(frame); err != nil {
+				return err
+			}
+			continue
+		}
+		doc.Write(frame)
+		if command == cmd.XS_CMD_INDEX_SUBMIT {
+			docs++
+			if err := im.bulk.write(doc.Bytes(), ""); err != nil {
+				return err
+			}
+			doc = bytes.NewBuffer([]byte{})
+		}
+	}
+	if doc.Len() > 0 {
+		// 最后一个文档缺少 XS_CMD_INDEX_SUBMIT, 不完整的请求不能提交给服务端
+		return &ImportError{docs + 1, fmt.Errorf("truncated document")}
+	}
+	return nil
+}
+
+// readFrame 读取一条编码后的指令
+func readFrame(reader io.Reader) (uint8, []byte, error) {
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(reader, head); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return 0, nil, fmt.Errorf("truncated command: %w", err)
+		}
+		return 0, nil, err
+	}
+	len1, len2, err := cmd.DecodeHead(head)
+	if err != nil {
+		return 0, nil, err
+	}
+	if int(len1) > maxBulkBytes {
+		return 0, nil, fmt.Errorf("command too large: %d bytes", len1)
+	}
+	frame := make([]byte, 8+int(len1)+int(len2))
+	copy(frame, head)
+	if _, err := io.ReadFull(reader, frame[8:]); err != nil {
+		return 0, nil, fmt.Errorf("truncated command: %w", err)
+	}
+	return head[0], frame, nil
+}
@@ -3,6 +3,7 @@ package xs4go
 import (
 	"bytes"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/ninggf/xs4go/cmd"
@@ -21,6 +22,8 @@ type Indexer struct {
 	tokenizer  tokenizer.Tokenizer
 	bufferSize uint32
 	rebuilding bool
+	curDB      string
+	structs    map[reflect.Type]error
 }
 
 // NewIndexer creates a Indexer
@@ -63,6 +66,9 @@ func (indexer *Indexer) SetDB(db string) error {
 	cmdx.Buf = db
 
 	_, err := indexer.conn.ExecOK(&cmdx, cmd.XS_CMD_OK_DB_CHANGED)
+	if err == nil {
+		indexer.curDB = db
+	}
 	return err
 }
 
@@ -84,11 +90,25 @@ func (indexer *Indexer) FlushIndex() error {
 
 // Add document to index server
 func (indexer *Indexer) Add(doc map[string]string) error {
-	return indexer.update(doc, true)
+	return indexer.update(schema.NewIndexDocument(doc), true)
 }
 
 // Update document by id on index server
 func (indexer *Indexer) Update(doc map[string]string) error {
+	return indexer.update(schema.NewIndexDocument(doc), false)
+}
+
+// AddDocument 添加文档到索引, 文档通过 AddTerm/AddIndex 附加的索引词和索引文本会一同提交
+//
+//	doc := schema.NewIndexDocument(map[string]string{"id": "1", "title": "西湖"})
+//	doc.AddTerm("title", "杭州", 2)
+//	indexer.AddDocument(doc)
+func (indexer *Indexer) AddDocument(doc *schema.Document) error {
+	return indexer.update(doc, true)
+}
+
+// UpdateDocument 以文档的主键更新索引中的文档, 参见 AddDocument
+func (indexer *Indexer) UpdateDocument(doc *schema.Document) error {
 	return indexer.update(doc, false)
 }
 
@@ -164,6 +184,9 @@ func (indexer *Indexer) DelSynonym(word string, synonyms ...string) error {
 }
 
 // OpenBuffer open buffer for improving performance
+//
+// size 为缓冲区大小(MB), 最大为 32. 打开后 Add/Update 等操作先写入缓冲区,
+// 缓冲区满或调用 Submit 时一次性提交. size 为 0 时提交并关闭缓冲区
 func (indexer *Indexer) OpenBuffer(size uint32) error {
 	if size > 32 {
 		size = 32
@@ -181,7 +204,7 @@ func (indexer *Indexer) OpenBuffer(size uint32) error {
 	}
 
 	if size > 0 {
-		indexer.bufferSize = 30 //(size << 20)
+		indexer.bufferSize = size << 20
 		indexer.buffer = bytes.NewBuffer(make([]byte, 0, indexer.bufferSize))
 	} else {
 		indexer.bufferSize = 0
@@ -231,6 +254,18 @@ func (indexer *Indexer) StopRebuild() error {
 	return err
 }
 
+// DeleteProject 删除服务端的当前项目及其全部索引数据, 操作不可恢复
+//
+// 为防止误删, confirm 必须与当前项目名称一致. 删除后应关闭当前 Indexer
+func (indexer *Indexer) DeleteProject(confirm string) error {
+	if confirm != indexer.cfg.Name {
+		return fmt.Errorf("confirmation '%s' does not match the project name", confirm)
+	}
+	cmdx := cmd.NewCommand(cmd.XS_CMD_DELETE_PROJECT, 0)
+	_, err := indexer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_PROJECT_DEL)
+	return err
+}
+
 // Close connection
 func (indexer *Indexer) Close() {
 	if indexer.conn != nil {
@@ -243,7 +278,7 @@ func (indexer *Indexer) Close() {
 }
 
 func (indexer *Indexer) setProject(project string) (*Indexer, error) {
-	cmdx := cmd.UseProjectCmd(project)
+	cmdx := cmd.UseProjectWithHomeCmd(project, indexer.cfg.Home)
 
 	_, err := indexer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_PROJECT)
 
@@ -258,12 +293,37 @@ func (indexer *Indexer) setProject(project string) (*Indexer, error) {
 	return indexer, nil
 }
 
-func (indexer *Indexer) update(doc map[string]string, add bool) error {
+func (indexer *Indexer) update(doc *schema.Document, add bool) error {
+	cmds, err := indexer.docCmds(doc, add)
+	if err != nil {
+		return err
+	}
+	if indexer.buffer != nil {
+		return indexer.bufferWrite(encodeCmds(cmds))
+	}
+	last := len(cmds) - 1
+	for i := 0; i < last; i++ {
+		_, err := indexer.conn.ExecOK(cmds[i], cmd.XS_CMD_NONE)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = indexer.conn.ExecOK(cmds[last], cmd.XS_CMD_OK_RQST_FINISHED)
+	return err
+}
+
+// docCmds 生成添加或更新文档的全部指令, 最后一条为 XS_CMD_INDEX_SUBMIT
+func (indexer *Indexer) docCmds(doc *schema.Document, add bool) ([]*cmd.XsCommand, error) {
 	idField := indexer.setting.Schema.Id // id
-	key, ok := doc[idField.Name]
+	key, ok := doc.Fields[idField.Name]
 	// check primary key of document
 	if !ok || key == "" {
-		return fmt.Errorf("Missing value of primary key (FIELD:%s)", idField.Name)
+		return nil, fmt.Errorf("Missing value of primary key (FIELD:%s)", idField.Name)
+	}
+	for _, f := range doc.AddedFields() {
+		if _, ok := indexer.schema.FieldMetas[f]; !ok {
+			return nil, fmt.Errorf("field '%s' is not defined", f)
+		}
 	}
 
 	// request cmd
@@ -287,22 +347,20 @@ func (indexer *Indexer) update(doc map[string]string, add bool) error {
 		}
 		indexer.buildCmd(f, v, doc, cmds)
 	}
-	for i := 0; i < len(cmds); i++ {
-		icmd := cmds[i]
-		_, err := indexer.conn.ExecOK(icmd, cmd.XS_CMD_NONE)
-		if err != nil {
-			return err
-		}
-	}
-	// todo: submit cmd
+	// submit cmd
 	submitCmd := &cmd.XsCommand{}
 	submitCmd.Cmd = cmd.XS_CMD_INDEX_SUBMIT
-	_, err := indexer.conn.ExecOK(submitCmd, cmd.XS_CMD_OK_RQST_FINISHED)
-	return err
+	cmds[len(cmds)] = submitCmd
+
+	result := make([]*cmd.XsCommand, len(cmds))
+	for i := range result {
+		result[i] = cmds[i]
+	}
+	return result, nil
 }
 
-func (indexer *Indexer) buildCmd(f string, v *schema.FieldMeta, doc map[string]string, cmds map[int]*cmd.XsCommand) {
-	value, ok := doc[f]
+func (indexer *Indexer) buildCmd(f string, v *schema.FieldMeta, doc *schema.Document, cmds map[int]*cmd.XsCommand) {
+	value, ok := doc.Fields[f]
 	// 索引操作
 	if ok && value != "" { //找到对应的值
 		varg := uint8(0)
@@ -334,28 +392,79 @@ func (indexer *Indexer) buildCmd(f string, v *schema.FieldMeta, doc map[string]s
 		// add value
 		cmds[len(cmds)] = cmd.NewCommand2(cmd.XS_CMD_DOC_VALUE, varg, v.Vno, value, "")
 	}
-	// TODO: process add terms
-	// todo: process add text
+	// process add terms
+	if terms, ok := doc.GetAddTerms(f); ok {
+		wdf1 := uint8(0)
+		if !v.IsBoolIndex() {
+			wdf1 = cmd.XS_CMD_INDEX_FLAG_CHECKSTEM
+		}
+		for term, weight := range terms {
+			term = strings.ToLower(term)
+			if term == "" || len(term) > 200 {
+				continue
+			}
+			wdf2 := uint32(1)
+			if !v.IsBoolIndex() {
+				wdf2 = uint32(weight) * uint32(v.Weight)
+				if wdf2 == 0 {
+					wdf2 = 1
+				}
+			}
+			// 词频的有效位只有 6 位, 超出时分多次提交
+			for wdf2 > 0 {
+				wdf := wdf2
+				if wdf > 63 {
+					wdf = 63
+				}
+				cmds[len(cmds)] = cmd.NewCommand2(cmd.XS_CMD_DOC_TERM, wdf1|uint8(wdf), v.Vno, term, "")
+				wdf2 -= wdf
+			}
+		}
+	}
+	// process add text
+	// 原文交由服务端分词; DOC_INDEX 的 0x80 位表示同时保存为字段值, 因此不能设置
+	if text, ok := doc.GetAddIndex(f); ok {
+		wdf := uint8(v.Weight)
+		if v.WithPos() {
+			wdf |= cmd.XS_CMD_INDEX_FLAG_WITHPOS
+		}
+		if v.HasIndexSelf() {
+			cmds[len(cmds)] = cmd.NewCommand2(cmd.XS_CMD_DOC_INDEX, wdf, v.Vno, text, "")
+		}
+		if v.HasIndexMixed() {
+			cmds[len(cmds)] = cmd.NewCommand2(cmd.XS_CMD_DOC_INDEX, wdf, schema.MIXED_VNO, text, "")
+		}
+	}
 }
 
 func (indexer *Indexer) bufferExec(cmdx *cmd.XsCommand, resArg uint16) error {
 	if indexer.buffer != nil {
-		ln := indexer.buffer.Len()
-		buf := cmdx.Encode(false)
-		ln1 := len(buf)
-		if uint32(ln1+ln) > indexer.bufferSize {
-			err := indexer.flushBuffer()
-			if err != nil {
-				return err
-			}
-		}
-		indexer.buffer.Write(buf[:])
-		return nil
+		return indexer.bufferWrite(cmdx.Encode(false))
 	}
 	_, err := indexer.conn.ExecOK(cmdx, resArg)
 	return err
 }
 
+// bufferWrite 将已编码的指令写入缓冲区, 缓冲区将满时先提交
+func (indexer *Indexer) bufferWrite(buf []byte) error {
+	if uint32(len(buf)+indexer.buffer.Len()) > indexer.bufferSize {
+		if err := indexer.flushBuffer(); err != nil {
+			return err
+		}
+	}
+	indexer.buffer.Write(buf)
+	return nil
+}
+
+// encodeCmds 将多条指令编码后连接, 用作 XS_CMD_INDEX_EXDATA 的数据
+func encodeCmds(cmds []*cmd.XsCommand) []byte {
+	buf := bytes.NewBuffer([]byte{})
+	for _, cmdx := range cmds {
+		buf.Write(cmdx.Encode(false))
+	}
+	return buf.Bytes()
+}
+
 func (indexer *Indexer) flushBuffer() error {
 	if indexer.buffer != nil {
 		buf := string(indexer.buffer.Bytes())
@@ -0,0 +1,43 @@
+package xs4go
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SearchInto 执行搜索并将结果文档解码到 dst, dst 须为 *[]T 或 *[]*T, T 为带 xs 标签的结构体
+//
+// 解码规则参见 schema.Document.Decode, 返回的 SearchResult 中仍包含原始文档
+func (searcher *Searcher) SearchInto(dst interface{}, queries ...string) (*SearchResult, error) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return nil, fmt.Errorf("SearchInto: non-nil pointer to slice expected, got %T", dst)
+	}
+	slice := rv.Elem()
+	elem := slice.Type().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("SearchInto: slice of struct expected, got %T", dst)
+	}
+	res, err := searcher.Search(queries...)
+	if err != nil {
+		return nil, err
+	}
+	items := reflect.MakeSlice(slice.Type(), 0, len(res.Docs))
+	for _, doc := range res.Docs {
+		item := reflect.New(elem)
+		if err := doc.Decode(item.Interface()); err != nil {
+			return res, err
+		}
+		if isPtr {
+			items = reflect.Append(items, item)
+		} else {
+			items = reflect.Append(items, item.Elem())
+		}
+	}
+	slice.Set(items)
+	return res, nil
+}
@@ -0,0 +1,227 @@
+package xs4go
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+// ResultIterator 逐条读取搜索结果, 文档在调用 Next 时才从连接中解码
+//
+// 迭代结束(Next 返回 false)或调用 Close 之前, 不能在同一个 Searcher 上执行其它操作
+type ResultIterator struct {
+	searcher *Searcher
+	begin    *cmd.XsCommand
+	vnomap   map[uint8]string
+	dbs      []string
+	query    string
+	total    uint32
+	doc      *schema.Document
+	pending  *schema.Document
+	err      error
+	done     bool
+}
+
+// Iterate 执行搜索并返回结果迭代器, 参数与 Search 相同
+//
+//	it, err := searcher.Iterate("杭州")
+//	for it.Next() {
+//		doc := it.Doc()
+//	}
+//	err = it.Err()
+func (searcher *Searcher) Iterate(queries ...string) (*ResultIterator, error) {
+	query := strings.Join(queries, " AND ")
+	if searcher.curDB != logDB {
+		searcher.lastHlQuery = query
+		searcher.hlTerms = nil
+	}
+	if query != "" {
+		query = searcher.preQueryString(query)
+	}
+	if searcher.limit == 0 {
+		searcher.limit = 10
+	}
+	page, _ := cmd.Pack("II", searcher.offset, searcher.limit)
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_GET_RESULT, 0, searcher.defaultOp, query, page)
+	res, err := searcher.conn.ExecOK(cmdx, cmd.XS_CMD_OK_RESULT_BEGIN)
+	if err != nil {
+		return nil, err
+	}
+	resx, err := cmd.UnPack("Icount", res.Buf)
+	if err != nil {
+		return nil, err
+	}
+	searcher.lastCount = resx["count"].(uint32)
+	searcher.Facets = make(map[string]Facet)
+
+	var currSchema *schema.Schema
+	if searcher.curDB == logDB {
+		currSchema = searcher.setting.Logger
+	} else {
+		currSchema = searcher.setting.Schema
+	}
+	return &ResultIterator{
+		searcher: searcher,
+		begin:    res,
+		vnomap:   currSchema.VnoMap(),
+		dbs:      searcher.ListDBs(),
+		query:    query,
+		total:    searcher.lastCount,
+	}, nil
+}
+
+// Next 读取下一个文档, 没有更多文档或出错时返回 false
+func (it *ResultIterator) Next() bool {
+	for {
+		if !it.read() {
+			return false
+		}
+		if it.searcher.geo == nil || it.searcher.geo.accept(it.doc) {
+			return true
+		}
+	}
+}
+
+// Doc 返回当前文档
+func (it *ResultIterator) Doc() *schema.Document {
+	return it.doc
+}
+
+// Err 返回迭代过程中出现的错误
+func (it *ResultIterator) Err() error {
+	return it.err
+}
+
+// Total 返回匹配总数的估值
+func (it *ResultIterator) Total() uint32 {
+	return it.total
+}
+
+// Close 丢弃剩余的结果, 使连接可以继续使用
+func (it *ResultIterator) Close() error {
+	for it.read() {
+	}
+	return it.err
+}
+
+// read 读取响应直到一个文档完整(遇到下一个文档或结束标志)
+func (it *ResultIterator) read() bool {
+	it.doc = nil
+	if it.done || it.err != nil {
+		return false
+	}
+	for {
+		mres, err := it.searcher.conn.GetSearchResponse(it.begin)
+		if err != nil {
+			return it.fail(err, false)
+		}
+		switch {
+		case mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_FACETS:
+			it.searcher.decodeFacets(mres.Buf, it.vnomap)
+		case mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_DOC:
+			doc, err := schema.NewDocument(mres.Buf)
+			if err != nil {
+				return it.fail(err, true)
+			}
+			doc.DB = docDB(it.dbs, doc.Docid)
+			it.doc, it.pending = it.pending, doc
+			if it.doc != nil {
+				return true
+			}
+		case mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_FIELD:
+			if it.pending != nil {
+				fname, ok := it.vnomap[uint8(mres.GetArg())]
+				if !ok {
+					fname = strconv.Itoa(int(mres.GetArg()))
+				}
+				it.pending.Fields[fname] = mres.Buf
+			}
+		case mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_MATCHED:
+			if it.pending != nil {
+				it.pending.Matched = strings.Split(mres.Buf, " ")
+			}
+		case mres.Cmd == cmd.XS_CMD_OK && cmd.XS_CMD_OK_RESULT_END == mres.GetArg():
+			it.done = true
+			it.finish()
+			it.doc, it.pending = it.pending, nil
+			return it.doc != nil
+		case mres.Cmd == cmd.XS_CMD_ERR:
+			// 服务端出错时不再返回其余结果
+			it.done = true
+			it.doc, it.pending = nil, nil
+			it.err = errors.New(mres.Buf)
+			return false
+		default:
+			return it.fail(fmt.Errorf("Unexpected respond in search :%v", mres), false)
+		}
+	}
+}
+
+// fail 记录错误并丢弃剩余的结果. drain 为 true 时读取到结束标志为止,
+// 否则(或读取失败时)无法确定剩余响应的边界, 只能关闭连接, 以免后续指令读到错位的响应
+func (it *ResultIterator) fail(err error, drain bool) bool {
+	it.err = err
+	it.doc, it.pending = nil, nil
+	for drain {
+		mres, rerr := it.searcher.conn.GetSearchResponse(it.begin)
+		if rerr != nil {
+			break
+		}
+		if mres.Cmd == cmd.XS_CMD_ERR || (mres.Cmd == cmd.XS_CMD_OK && cmd.XS_CMD_OK_RESULT_END == mres.GetArg()) {
+			return false
+		}
+	}
+	it.searcher.conn.Close()
+	return false
+}
+
+func (it *ResultIterator) finish() {
+	searcher := it.searcher
+	if it.query == "" && searcher.curDB != logDB {
+		searcher.count = searcher.lastCount
+		searcher.logQuery()
+	}
+}
+
+// fetch 读取一页文档, 不附带 Search 的词汇和纠错等信息
+func (searcher *Searcher) fetch(query string) ([]*schema.Document, error) {
+	it, err := searcher.Iterate(query)
+	if err != nil {
+		return nil, err
+	}
+	docs := []*schema.Document{}
+	for it.Next() {
+		docs = append(docs, it.Doc())
+	}
+	return docs, it.Err()
+}
+
+// decodeFacets 解析分面统计结果并保存到 Facets
+func (searcher *Searcher) decodeFacets(buf string, vnomap map[uint8]string) {
+	off := 0
+	ln := len(buf)
+	for (off + 6) < ln {
+		facts, err := cmd.UnPack("Cvno/Cvlen/Inum", buf[off:off+6])
+		if err != nil {
+			break
+		}
+		vno := facts["vno"].(uint8)
+		vlen := int(facts["vlen"].(uint8))
+		if off+6+vlen > ln {
+			break
+		}
+		if fname, ok := vnomap[vno]; ok {
+			facet, ok1 := searcher.Facets[fname]
+			if !ok1 {
+				facet = Facet{}
+				searcher.Facets[fname] = facet
+			}
+			facet[buf[off+6:off+6+vlen]] = int32(facts["num"].(uint32))
+		}
+		off += vlen + 6
+	}
+}
@@ -8,11 +8,58 @@ import (
 	"sync"
 
 	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/query"
 	"github.com/ninggf/xs4go/schema"
 )
 
 var mux sync.Mutex
 
+// queryResolver 为 query.Compiler 提供字段定义, 并登记用到的字段前缀
+type queryResolver struct {
+	searcher *Searcher
+}
+
+func (resolver queryResolver) Field(name string) (*schema.FieldMeta, bool) {
+	field, ok := resolver.searcher.schema.FieldMetas[name]
+	if ok {
+		resolver.searcher.regQueryPrefix(name)
+	}
+	return field, ok
+}
+
+// SetQueryNode 用语法树设置默认搜索语句
+//
+// 与 SetQuery 不同, 会先清除之前设置的搜索语句及 AddRange、AddWeight 等附加条件.
+// 顶层节点可使用 query.AndMaybe 和 query.Filter
+func (searcher *Searcher) SetQueryNode(node query.Node) error {
+	compiler := &query.Compiler{Resolver: queryResolver{searcher}, DefaultOp: searcher.defaultOp}
+	cmds, err := compiler.CompileAll(node)
+	if err != nil {
+		return err
+	}
+	searcher.clearQuery()
+	for _, cmdx := range cmds {
+		if _, err := searcher.conn.ExecOK(cmdx, 0); err != nil {
+			return err
+		}
+	}
+	searcher.query = node.String()
+	return nil
+}
+
+// AddQueryNode 以 addOp 将语法树合并到默认搜索语句
+//
+// addOp 可选值同 AddQueryString, scale 为权重缩放比例, 1 表示不缩放
+func (searcher *Searcher) AddQueryNode(node query.Node, addOp uint8, scale float32) error {
+	compiler := &query.Compiler{Resolver: queryResolver{searcher}, DefaultOp: searcher.defaultOp, Scale: scale}
+	cmdx, err := compiler.Compile(node, addOp)
+	if err != nil {
+		return err
+	}
+	_, err = searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
 // preQueryString
 //
 // 搜索语句的准备工作,登记相关的字段前缀并给非布尔字段补上括号
@@ -55,6 +102,12 @@ func (searcher *Searcher) preQueryString(query string) string {
 					value = part[pos+1 : len(part)-1]
 				}
 
+				if (field.IsNumeric() || field.IsDate()) && strings.Contains(value, "..") {
+					// 区间检索, 交由服务端的区间处理器解析
+					newQuery.WriteString(part)
+					continue
+				}
+
 				terms := searcher.tokenizer.GetTokens(value)
 				for i, term := range terms {
 					terms[i] = strings.ToLower(term)
@@ -111,6 +164,9 @@ func (searcher *Searcher) initSpecialField() {
 		}
 		if field.IsNumeric() {
 			searcher.conn.ExecOK(cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_NUMERIC, 0, field.Vno), 0)
+			searcher.addRangeProcessor(field, cmd.XS_CMD_RANGE_PROC_NUMBER)
+		} else if field.IsDate() {
+			searcher.addRangeProcessor(field, cmd.XS_CMD_RANGE_PROC_DATE)
 		}
 	}
 }
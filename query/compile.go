@@ -0,0 +1,203 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+// Resolver 提供编译时所需的字段定义, 通常由 Searcher 实现
+type Resolver interface {
+	// Field 返回字段定义, 并确保该字段的搜索前缀已在服务端登记
+	Field(name string) (*schema.FieldMeta, bool)
+}
+
+// Compiler 将语法树编译为 XS_CMD_QUERY_* 指令
+type Compiler struct {
+	Resolver  Resolver
+	DefaultOp uint8 // 解析搜索语句时词与词之间的默认连接方式
+	Scale     float32
+}
+
+// Compile 将 node 编译为一条以 addOp 合并到服务端当前搜索语句的指令
+//
+// 单个词汇编译为 XS_CMD_QUERY_TERM, 区间编译为 XS_CMD_QUERY_RANGE/XS_CMD_QUERY_VALCMP,
+// 其它节点(包括需要分词的中日韩文字)编译为 XS_CMD_QUERY_PARSE, 由服务端分词
+func (compiler *Compiler) Compile(node Node, addOp uint8) (*cmd.XsCommand, error) {
+	switch n := node.(type) {
+	case *TermNode:
+		if cmdx, err := compiler.term(n.Field, n.Text, addOp); cmdx != nil || err != nil {
+			return cmdx, err
+		}
+	case *RangeNode:
+		return compiler.valueRange(n, addOp)
+	case *FieldNode:
+		if t, ok := n.Node.(*TermNode); ok && t.Field == "" {
+			if cmdx, err := compiler.term(n.Name, t.Text, addOp); cmdx != nil || err != nil {
+				return cmdx, err
+			}
+		}
+	}
+	if err := compiler.check(node); err != nil {
+		return nil, err
+	}
+	w := &writer{bytes.NewBufferString(""), compiler.mixed, compiler.boolIndex}
+	node.render(w, "")
+	text := w.String()
+	if text == "" {
+		return nil, fmt.Errorf("empty query")
+	}
+	return cmd.NewCommand2(cmd.XS_CMD_QUERY_PARSE, addOp, compiler.DefaultOp, text, compiler.scale()), nil
+}
+
+// CompileAll 将 node 编译为多条指令, 要求服务端当前搜索语句为空
+//
+// 顶层布尔节点的各子节点依次以该节点的运算符合并, 因此 AndMaybe 和 Filter 也可以使用
+func (compiler *Compiler) CompileAll(node Node) ([]*cmd.XsCommand, error) {
+	return compiler.fold(node, cmd.XS_CMD_QUERY_OP_AND, []*cmd.XsCommand{})
+}
+
+func (compiler *Compiler) fold(node Node, addOp uint8, cmds []*cmd.XsCommand) ([]*cmd.XsCommand, error) {
+	n, ok := node.(*BoolNode)
+	if !ok || len(n.Nodes) == 0 {
+		cmdx, err := compiler.Compile(node, addOp)
+		if err != nil {
+			return cmds, err
+		}
+		return append(cmds, cmdx), nil
+	}
+	cmds, err := compiler.fold(n.Nodes[0], addOp, cmds)
+	if err != nil {
+		return cmds, err
+	}
+	for _, child := range n.Nodes[1:] {
+		cmdx, err := compiler.Compile(child, n.Op)
+		if err != nil {
+			return cmds, err
+		}
+		cmds = append(cmds, cmdx)
+	}
+	return cmds, nil
+}
+
+// term 将单个词汇编译为 XS_CMD_QUERY_TERM, 布尔型字段(如 id)的值整体作为一个词.
+// 无法作为单个词汇处理(如多个词或需要分词的中日韩文字)时返回 nil
+func (compiler *Compiler) term(field, text string, addOp uint8) (*cmd.XsCommand, error) {
+	vno := uint8(schema.MIXED_VNO)
+	if field != "" {
+		f, err := compiler.field(field)
+		if err != nil {
+			return nil, err
+		}
+		vno = f.Vno
+		if f.IsBoolIndex() && text != "" {
+			return cmd.NewCommand2(cmd.XS_CMD_QUERY_TERM, addOp, vno, strings.ToLower(text), compiler.scale()), nil
+		}
+	}
+	words := words(text)
+	if len(words) != 1 || hasCJK(words[0]) {
+		return nil, nil
+	}
+	return cmd.NewCommand2(cmd.XS_CMD_QUERY_TERM, addOp, vno, words[0], compiler.scale()), nil
+}
+
+func (compiler *Compiler) valueRange(node *RangeNode, addOp uint8) (*cmd.XsCommand, error) {
+	f, err := compiler.field(node.Field)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.From) > 255 || len(node.To) > 255 {
+		return nil, fmt.Errorf("value of range is too long")
+	}
+	if node.From == "" && node.To == "" {
+		return nil, fmt.Errorf("empty range of field '%s'", node.Field)
+	} else if node.From == "" {
+		return cmd.NewCommand2(cmd.XS_CMD_QUERY_VALCMP, addOp, f.Vno, node.To, string([]byte{cmd.XS_CMD_VALCMP_LE})), nil
+	} else if node.To == "" {
+		return cmd.NewCommand2(cmd.XS_CMD_QUERY_VALCMP, addOp, f.Vno, node.From, string([]byte{cmd.XS_CMD_VALCMP_GE})), nil
+	}
+	return cmd.NewCommand2(cmd.XS_CMD_QUERY_RANGE, addOp, f.Vno, node.From, node.To), nil
+}
+
+// check 检查将被编译为搜索语句的节点: 字段须已定义, 且不能包含无对应语法的节点
+func (compiler *Compiler) check(node Node) error {
+	switch n := node.(type) {
+	case *TermNode:
+		return compiler.checkField(n.Field)
+	case *PhraseNode:
+		return compiler.checkField(n.Field)
+	case *WildcardNode:
+		return compiler.checkField(n.Field)
+	case *FieldNode:
+		if err := compiler.checkField(n.Name); err != nil {
+			return err
+		}
+		if n.Node != nil {
+			return compiler.check(n.Node)
+		}
+	case *RangeNode:
+		f, err := compiler.field(n.Field)
+		if err != nil {
+			return err
+		}
+		if !f.IsNumeric() && !f.IsDate() {
+			return fmt.Errorf("range of field '%s' can only be used at top level", n.Field)
+		}
+	case *NearNode:
+		for _, child := range n.Nodes {
+			if err := compiler.check(child); err != nil {
+				return err
+			}
+		}
+	case *BoolNode:
+		if n.Op == cmd.XS_CMD_QUERY_OP_AND_MAYBE || n.Op == cmd.XS_CMD_QUERY_OP_FILTER {
+			return fmt.Errorf("AndMaybe and Filter can only be used at top level")
+		}
+		for _, child := range n.Nodes {
+			if err := compiler.check(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (compiler *Compiler) checkField(name string) error {
+	if name == "" {
+		return nil
+	}
+	_, err := compiler.field(name)
+	return err
+}
+
+func (compiler *Compiler) field(name string) (*schema.FieldMeta, error) {
+	if compiler.Resolver != nil {
+		if f, ok := compiler.Resolver.Field(name); ok {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("field '%s' is not defined", name)
+}
+
+func (compiler *Compiler) mixed(field string) bool {
+	f, err := compiler.field(field)
+	return err == nil && f.Vno == schema.MIXED_VNO
+}
+
+func (compiler *Compiler) boolIndex(field string) bool {
+	f, err := compiler.field(field)
+	return err == nil && f.IsBoolIndex()
+}
+
+func (compiler *Compiler) scale() string {
+	scale := compiler.Scale
+	if scale > 0 && scale != 1 && scale < 655.35 {
+		if pd, err := cmd.Pack("n", uint16(scale*100)); err == nil {
+			return pd
+		}
+	}
+	return ""
+}
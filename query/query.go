@@ -0,0 +1,345 @@
+// Package query 提供结构化的搜索语句构造器
+//
+// 用语法树代替手工拼接搜索语句, 用户输入的文本中的冒号、括号、引号及布尔运算符等
+// 都会被当作普通文本处理, 不会破坏搜索语句的结构.
+package query
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ninggf/xs4go/cmd"
+)
+
+// Node 搜索语句语法树的节点
+type Node interface {
+	// String 返回该节点对应的搜索语句
+	String() string
+	render(w *writer, field string)
+}
+
+// TermNode 单个词汇, 为空字段时在混合区中搜索
+type TermNode struct {
+	Field string
+	Text  string
+}
+
+// PhraseNode 短语, 要求词汇按顺序相邻出现
+type PhraseNode struct {
+	Field string
+	Text  string
+}
+
+// WildcardNode 前缀通配, 需开启 XS_CMD_PARSE_FLAG_WILDCARD
+type WildcardNode struct {
+	Field  string
+	Prefix string
+}
+
+// FieldNode 将子节点中未指定字段的词汇限定在字段 Name 中搜索
+type FieldNode struct {
+	Name string
+	Node Node
+}
+
+// RangeNode 字段值区间, From 或 To 为空表示该端不限制
+type RangeNode struct {
+	Field string
+	From  string
+	To    string
+}
+
+// NearNode 多个词汇在 Distance 个词的距离内出现, Ordered 为 true 时还要求按顺序出现
+type NearNode struct {
+	Nodes    []Node
+	Distance int
+	Ordered  bool
+}
+
+// BoolNode 用布尔运算连接多个子节点, Op 为 XS_CMD_QUERY_OP_* 之一
+type BoolNode struct {
+	Op    uint8
+	Nodes []Node
+}
+
+// Term 创建词汇节点
+func Term(text string) *TermNode {
+	return &TermNode{Text: text}
+}
+
+// Phrase 创建短语节点
+func Phrase(text string) *PhraseNode {
+	return &PhraseNode{Text: text}
+}
+
+// Wildcard 创建前缀通配节点
+func Wildcard(prefix string) *WildcardNode {
+	return &WildcardNode{Prefix: prefix}
+}
+
+// Field 创建字段节点
+func Field(name string, node Node) *FieldNode {
+	return &FieldNode{name, node}
+}
+
+// Range 创建区间节点
+func Range(field, from, to string) *RangeNode {
+	return &RangeNode{field, from, to}
+}
+
+// Near 创建近邻节点, distance 为 0 时默认为 10
+func Near(distance int, nodes ...Node) *NearNode {
+	return &NearNode{Nodes: nodes, Distance: distance}
+}
+
+// And 所有子节点都必须匹配
+func And(nodes ...Node) *BoolNode {
+	return &BoolNode{cmd.XS_CMD_QUERY_OP_AND, nodes}
+}
+
+// Or 任意子节点匹配即可
+func Or(nodes ...Node) *BoolNode {
+	return &BoolNode{cmd.XS_CMD_QUERY_OP_OR, nodes}
+}
+
+// AndNot 匹配第一个子节点, 且不匹配其余子节点
+func AndNot(nodes ...Node) *BoolNode {
+	return &BoolNode{cmd.XS_CMD_QUERY_OP_AND_NOT, nodes}
+}
+
+// Xor 只匹配其中一个子节点
+func Xor(nodes ...Node) *BoolNode {
+	return &BoolNode{cmd.XS_CMD_QUERY_OP_XOR, nodes}
+}
+
+// AndMaybe 匹配第一个子节点, 同时匹配其余子节点的文档排名更靠前
+//
+// 搜索语句中没有对应的语法, 只能作为 Searcher.SetQueryNode 的顶层节点(或顶层节点的首个子节点)
+func AndMaybe(nodes ...Node) *BoolNode {
+	return &BoolNode{cmd.XS_CMD_QUERY_OP_AND_MAYBE, nodes}
+}
+
+// Filter 匹配第一个子节点, 并用其余子节点过滤结果, 过滤条件不参与排名计算
+//
+// 与 AndMaybe 一样只能作为顶层节点使用
+func Filter(nodes ...Node) *BoolNode {
+	return &BoolNode{cmd.XS_CMD_QUERY_OP_FILTER, nodes}
+}
+
+// String 返回该节点对应的搜索语句
+func (node *TermNode) String() string {
+	return toString(node)
+}
+
+// String 返回该节点对应的搜索语句
+func (node *PhraseNode) String() string {
+	return toString(node)
+}
+
+// String 返回该节点对应的搜索语句
+func (node *WildcardNode) String() string {
+	return toString(node)
+}
+
+// String 返回该节点对应的搜索语句
+func (node *FieldNode) String() string {
+	return toString(node)
+}
+
+// String 返回该节点对应的搜索语句
+func (node *RangeNode) String() string {
+	return toString(node)
+}
+
+// String 返回该节点对应的搜索语句
+func (node *NearNode) String() string {
+	return toString(node)
+}
+
+// String 返回该节点对应的搜索语句
+func (node *BoolNode) String() string {
+	return toString(node)
+}
+
+func (node *TermNode) render(w *writer, field string) {
+	field = pick(node.Field, field)
+	if node.Text != "" && w.boolIndex != nil && w.boolIndex(field) {
+		// 布尔型字段的值不分词, 整体作为一个词, 引号在值中需写为两个
+		w.writePrefix(field)
+		w.WriteByte('"')
+		w.WriteString(strings.Replace(strings.ToLower(node.Text), "\"", "\"\"", -1))
+		w.WriteByte('"')
+		return
+	}
+	words := words(node.Text)
+	if len(words) == 0 {
+		return
+	}
+	if len(words) > 1 {
+		w.WriteByte('(')
+	}
+	for i, word := range words {
+		if i > 0 {
+			w.WriteByte(' ')
+		}
+		w.writePrefix(field)
+		if isOperator(word) {
+			w.WriteString("\"" + word + "\"")
+		} else {
+			w.WriteString(word)
+		}
+	}
+	if len(words) > 1 {
+		w.WriteByte(')')
+	}
+}
+
+func (node *PhraseNode) render(w *writer, field string) {
+	words := words(node.Text)
+	if len(words) == 0 {
+		return
+	}
+	w.writePrefix(pick(node.Field, field))
+	w.WriteByte('"')
+	w.WriteString(strings.Join(words, " "))
+	w.WriteByte('"')
+}
+
+func (node *WildcardNode) render(w *writer, field string) {
+	words := words(node.Prefix)
+	if len(words) == 0 {
+		return
+	}
+	w.writePrefix(pick(node.Field, field))
+	w.WriteString(strings.Join(words, ""))
+	w.WriteByte('*')
+}
+
+func (node *FieldNode) render(w *writer, field string) {
+	if node.Node != nil {
+		node.Node.render(w, node.Name)
+	}
+}
+
+func (node *RangeNode) render(w *writer, field string) {
+	if node.From == "" && node.To == "" {
+		return
+	}
+	w.writePrefix(node.Field)
+	w.WriteString(rangeValue(node.From))
+	w.WriteString("..")
+	w.WriteString(rangeValue(node.To))
+}
+
+func (node *NearNode) render(w *writer, field string) {
+	op := " NEAR/"
+	if node.Ordered {
+		op = " ADJ/"
+	}
+	distance := node.Distance
+	if distance <= 0 {
+		distance = 10
+	}
+	renderJoin(w, node.Nodes, op+strconv.Itoa(distance)+" ", field)
+}
+
+func (node *BoolNode) render(w *writer, field string) {
+	op := " AND "
+	switch node.Op {
+	case cmd.XS_CMD_QUERY_OP_OR:
+		op = " OR "
+	case cmd.XS_CMD_QUERY_OP_AND_NOT:
+		op = " AND NOT "
+	case cmd.XS_CMD_QUERY_OP_XOR:
+		op = " XOR "
+	}
+	renderJoin(w, node.Nodes, op, field)
+}
+
+func renderJoin(w *writer, nodes []Node, op string, field string) {
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		part := bytes.NewBufferString("")
+		node.render(&writer{part, w.mixed, w.boolIndex}, field)
+		if part.Len() > 0 {
+			parts = append(parts, part.String())
+		}
+	}
+	if len(parts) == 1 {
+		w.WriteString(parts[0])
+	} else if len(parts) > 1 {
+		w.WriteByte('(')
+		w.WriteString(strings.Join(parts, op))
+		w.WriteByte(')')
+	}
+}
+
+// writer 输出搜索语句, mixed 用于判断字段是否属于混合区(混合区字段不加前缀),
+// boolIndex 用于判断字段是否为布尔型(值不分词)
+type writer struct {
+	*bytes.Buffer
+	mixed     func(field string) bool
+	boolIndex func(field string) bool
+}
+
+func toString(node Node) string {
+	w := &writer{bytes.NewBufferString(""), nil, nil}
+	node.render(w, "")
+	return w.String()
+}
+
+func pick(field, parent string) string {
+	if field != "" {
+		return field
+	}
+	return parent
+}
+
+func (w *writer) writePrefix(field string) {
+	if field != "" && (w.mixed == nil || !w.mixed(field)) {
+		w.WriteString(field)
+		w.WriteByte(':')
+	}
+}
+
+// words 将用户输入拆分为词, 除字母和数字外的字符都视为分隔符, 英文字母转为小写
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+}
+
+// hasCJK 判断词中是否有中日韩文字, 这类文字之间没有分隔符, 需要由服务端分词
+func hasCJK(word string) bool {
+	for _, r := range word {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeValue 清理区间的边界值, 只保留字母、数字、小数点和负号
+func rangeValue(text string) string {
+	value := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' {
+			return r
+		}
+		return -1
+	}, text)
+	for strings.Contains(value, "..") {
+		value = strings.Replace(value, "..", ".", -1)
+	}
+	return value
+}
+
+// isOperator 判断词是否会被当作布尔运算符(开启 BOOLEAN_ANY_CASE 时小写也是运算符)
+func isOperator(word string) bool {
+	switch word {
+	case "and", "or", "not", "xor", "near", "adj":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+type testResolver map[string]*schema.FieldMeta
+
+func (resolver testResolver) Field(name string) (*schema.FieldMeta, bool) {
+	f, ok := resolver[name]
+	return f, ok
+}
+
+func newTestResolver() testResolver {
+	return testResolver{
+		"id":      &schema.FieldMeta{Field: schema.Field{Type: "id"}, Name: "id", Vno: 0},
+		"title":   &schema.FieldMeta{Field: schema.Field{Type: "title"}, Name: "title", Vno: 1},
+		"price":   &schema.FieldMeta{Field: schema.Field{Type: "numeric"}, Name: "price", Vno: 2},
+		"tag":     &schema.FieldMeta{Field: schema.Field{Type: "string"}, Name: "tag", Vno: 3},
+		"message": &schema.FieldMeta{Field: schema.Field{Type: "body"}, Name: "message", Vno: schema.MIXED_VNO},
+	}
+}
+
+func TestNode_String(t *testing.T) {
+	tests := []struct {
+		name string
+		node Node
+		want string
+	}{
+		{"term", Term("杭州"), "杭州"},
+		{"term with syntax", Term("title:(hack) OR"), "(title hack \"or\")"},
+		{"phrase", Phrase("\"西湖\" 美景"), "\"西湖 美景\""},
+		{"field", Field("title", Or(Term("西湖"), Wildcard("xi"))), "(title:西湖 OR title:xi*)"},
+		{"and not", AndNot(Term("杭州"), Term("汽车"), Term("火车")), "(杭州 AND NOT 汽车 AND NOT 火车)"},
+		{"near", Near(3, Term("杭州"), Term("西湖")), "(杭州 NEAR/3 西湖)"},
+		{"range", Range("price", "10", "(100)"), "price:10..100"},
+		{"empty child", And(Term("杭州"), Term("::")), "杭州"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.String(); got != tt.want {
+				t.Errorf("String() = %v, want = %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompiler_CompileAll(t *testing.T) {
+	compiler := &Compiler{Resolver: newTestResolver()}
+	node := Filter(AndMaybe(Field("title", Term("西湖")), Term("official")), Or(Term("a"), Term("b")), Range("price", "", "100"))
+	cmds, err := compiler.CompileAll(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cmd.XsCommand{
+		{Cmd: cmd.XS_CMD_QUERY_PARSE, Arg1: cmd.XS_CMD_QUERY_OP_AND, Buf: "title:西湖"},
+		{Cmd: cmd.XS_CMD_QUERY_TERM, Arg1: cmd.XS_CMD_QUERY_OP_AND_MAYBE, Arg2: schema.MIXED_VNO, Buf: "official"},
+		{Cmd: cmd.XS_CMD_QUERY_PARSE, Arg1: cmd.XS_CMD_QUERY_OP_FILTER, Buf: "(a OR b)"},
+		{Cmd: cmd.XS_CMD_QUERY_VALCMP, Arg1: cmd.XS_CMD_QUERY_OP_FILTER, Arg2: 2, Buf: "100", Buf1: string([]byte{cmd.XS_CMD_VALCMP_LE})},
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("len(cmds) = %v, want = %v", len(cmds), len(want))
+	}
+	for i := range want {
+		if *cmds[i] != want[i] {
+			t.Errorf("cmds[%d] = %v, want = %v", i, cmds[i], &want[i])
+		}
+	}
+}
+
+func TestCompiler_Compile(t *testing.T) {
+	compiler := &Compiler{Resolver: newTestResolver()}
+	cmdx, err := compiler.Compile(Field("id", Term("ABC-123")), cmd.XS_CMD_QUERY_OP_AND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmdx.Cmd != cmd.XS_CMD_QUERY_TERM || cmdx.Buf != "abc-123" {
+		t.Errorf("unexpected command %v", cmdx)
+	}
+	// 中日韩文字需要服务端分词
+	cmdx, err = compiler.Compile(Term("杭州西湖"), cmd.XS_CMD_QUERY_OP_AND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmdx.Cmd != cmd.XS_CMD_QUERY_PARSE || cmdx.Buf != "杭州西湖" {
+		t.Errorf("unexpected command %v", cmdx)
+	}
+	// 布尔型字段的值在组合节点中也不分词
+	cmdx, err = compiler.Compile(And(Field("id", Term("ABC-123")), Term("x")), cmd.XS_CMD_QUERY_OP_AND)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmdx.Cmd != cmd.XS_CMD_QUERY_PARSE || cmdx.Buf != "(id:\"abc-123\" AND x)" {
+		t.Errorf("unexpected command %v", cmdx)
+	}
+	cmdx, err = compiler.Compile(Field("message", And(Term("杭州"), Term("西湖"))), cmd.XS_CMD_QUERY_OP_OR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmdx.Cmd != cmd.XS_CMD_QUERY_PARSE || cmdx.Arg1 != cmd.XS_CMD_QUERY_OP_OR || cmdx.Buf != "(杭州 AND 西湖)" {
+		t.Errorf("unexpected command %v", cmdx)
+	}
+	if _, err := compiler.Compile(Or(Term("a"), Filter(Term("b"), Term("c"))), 0); err == nil {
+		t.Error("nested Filter should fail")
+	}
+	if _, err := compiler.Compile(And(Term("a"), Field("nofield", Term("b"))), 0); err == nil {
+		t.Error("undefined field should fail")
+	}
+	if _, err := compiler.Compile(Or(Term("a"), Range("tag", "a", "b")), 0); err == nil {
+		t.Error("nested range of string field should fail")
+	}
+}
@@ -0,0 +1,160 @@
+package xs4go
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
+)
+
+var (
+	valueRangeReg = regexp.MustCompile("(VALUE_RANGE) (\\d+) (\\S+) ([^)]+)")
+	valueCmpReg   = regexp.MustCompile("(VALUE_[GL]E) (\\d+) ([^)]+)")
+)
+
+// AddRange 添加字段值的区间过滤条件
+//
+// from, to 为区间的上下限(包含), 其中之一为空时表示该端不限制, 都为空时不做任何处理.
+// 与 SetQuery 组合使用, 对之后不带参数的 Search 和 Count 生效
+func (searcher *Searcher) AddRange(field, from, to string) error {
+	if from == "" && to == "" {
+		return nil
+	}
+	if len(from) > 255 || len(to) > 255 {
+		return fmt.Errorf("value of range is too long")
+	}
+	f, err := searcher.getField(field)
+	if err != nil {
+		return err
+	}
+	var cmdx *cmd.XsCommand
+	if from == "" {
+		cmdx = cmd.NewCommand2(cmd.XS_CMD_QUERY_VALCMP, cmd.XS_CMD_QUERY_OP_FILTER, f.Vno, to, string([]byte{cmd.XS_CMD_VALCMP_LE}))
+	} else if to == "" {
+		cmdx = cmd.NewCommand2(cmd.XS_CMD_QUERY_VALCMP, cmd.XS_CMD_QUERY_OP_FILTER, f.Vno, from, string([]byte{cmd.XS_CMD_VALCMP_GE}))
+	} else {
+		cmdx = cmd.NewCommand2(cmd.XS_CMD_QUERY_RANGE, cmd.XS_CMD_QUERY_OP_FILTER, f.Vno, from, to)
+	}
+	_, err = searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
+// addRangeProcessor 为数值或日期字段注册区间处理器,
+// 使搜索语句支持 price:10..100 或 date:20200101..20201231 形式的区间检索
+func (searcher *Searcher) addRangeProcessor(field *schema.FieldMeta, procType uint8) {
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_QUERY_RANGEPROC, procType, field.Vno, field.Name+":")
+	searcher.conn.ExecOK(cmdx, 0)
+}
+
+// formatValueRange 将解析后的搜索语句中的 VALUE_RANGE/VALUE_GE/VALUE_LE 转换为易读的 field:[from,to] 形式
+func (searcher *Searcher) formatValueRange(query string) string {
+	if strings.Contains(query, "VALUE_RANGE") {
+		query = cmd.ReplaceAllStringSubmatchFunc(valueRangeReg, query, searcher.formatValueRangeMatch)
+	}
+	if strings.Contains(query, "VALUE_GE") || strings.Contains(query, "VALUE_LE") {
+		query = cmd.ReplaceAllStringSubmatchFunc(valueCmpReg, query, searcher.formatValueRangeMatch)
+	}
+	return query
+}
+
+func (searcher *Searcher) formatValueRangeMatch(ms []string) string {
+	vno, err := strconv.Atoi(ms[2])
+	if err != nil {
+		return ms[0]
+	}
+	name, ok := searcher.schema.VnoMap()[uint8(vno)]
+	if !ok {
+		return ms[0]
+	}
+	field := searcher.schema.FieldMetas[name]
+	format := func(value string) string {
+		if field.IsNumeric() {
+			return strconv.FormatFloat(xapianUnserialise(value), 'f', -1, 64)
+		}
+		return value
+	}
+	val1, val2 := "~", "~"
+	if len(ms) > 4 {
+		val1, val2 = format(ms[3]), format(ms[4])
+	} else if ms[1] == "VALUE_LE" {
+		val2 = format(ms[3])
+	} else {
+		val1 = format(ms[3])
+	}
+	return name + ":[" + val1 + "," + val2 + "]"
+}
+
+// xapianUnserialise 将 xapian 的 sortable_serialise 编码还原为数值
+func xapianUnserialise(value string) float64 {
+	if value == "\x80" {
+		return 0
+	}
+	if value == strings.Repeat("\xff", 9) {
+		return math.Inf(1)
+	}
+	if value == "" {
+		return math.Inf(-1)
+	}
+	at := func(i int) uint32 {
+		if i < len(value) {
+			return uint32(value[i])
+		}
+		return 0
+	}
+	i := 0
+	c := at(0)
+	c ^= (c & 0xc0) >> 1
+	negative := c&0x80 == 0
+	exponentNegative := c&0x40 != 0
+	explen := c&0x20 == 0
+	exponent := int(c & 0x1f)
+	if !explen {
+		exponent >>= 2
+		if negative != exponentNegative {
+			exponent ^= 0x07
+		}
+	} else {
+		i++
+		c = at(i)
+		exponent <<= 6
+		exponent |= int(c >> 2)
+		if negative != exponentNegative {
+			exponent &= 0x07ff
+		}
+	}
+	word1 := (c & 0x03) << 24
+	word1 |= at(i+1) << 16
+	word1 |= at(i+2) << 8
+	word1 |= at(i + 3)
+	word2 := at(i+4)<<24 | at(i+5)<<16 | at(i+6)<<8 | at(i+7)
+
+	if !negative {
+		word1 |= 1 << 26
+	} else {
+		word1 = -word1
+		if word2 != 0 {
+			word1++
+		}
+		word2 = -word2
+		word1 &= 0x03ffffff
+	}
+	mantissa := float64(word2) / 4294967296.0
+	mantissa += float64(word1)
+	if negative {
+		mantissa /= 1 << 26
+	} else {
+		mantissa /= 1 << 27
+	}
+	if exponentNegative {
+		exponent = -exponent
+	}
+	exponent += 8
+	if negative {
+		mantissa = -mantissa
+	}
+	return math.Round(mantissa*math.Pow(2, float64(exponent))*100) / 100
+}
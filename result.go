@@ -0,0 +1,38 @@
+package xs4go
+
+import (
+	"time"
+
+	"github.com/ninggf/xs4go/schema"
+)
+
+// SearchResult 一次搜索的完整结果, 不依赖 Searcher 的状态, 可以安全地传递和并发读取
+type SearchResult struct {
+	Docs      []*schema.Document
+	Total     uint32           // 匹配总数的估值
+	Facets    map[string]Facet // 分面统计结果, 需先调用 SetFacets
+	Terms     []string         // 搜索语句中的词汇, 可用于高亮
+	Corrected []string         // 结果数量少于纠错阈值时的纠错建议, 参见 SetAutoCorrect
+	Elapsed   time.Duration
+	Query     string
+	// Suggestion 最佳纠错建议, 即 Corrected 的第一个
+	Suggestion string
+	// Substituted 为 true 时 Docs 等为以 Suggestion 重新搜索的结果, Query 仍为原搜索语句
+	Substituted bool
+}
+
+// GetFacets 获取字段的分面统计结果
+func (result *SearchResult) GetFacets(field string) Facet {
+	if facet, ok := result.Facets[field]; ok {
+		return facet
+	}
+	return Facet{}
+}
+
+// Highlight 以本次搜索的词汇高亮 text, opts 可以为 nil, opts.Terms 不为空时优先使用
+func (result *SearchResult) Highlight(text string, opts *HighlightOptions) string {
+	if opts != nil && len(opts.Terms) > 0 {
+		return HighlightTerms(text, opts.Terms, opts)
+	}
+	return HighlightTerms(text, result.Terms, opts)
+}
@@ -0,0 +1,72 @@
+package xs4go
+
+import (
+	"errors"
+
+	"github.com/ninggf/xs4go/schema"
+)
+
+// ErrStopScan 由 Scan 的回调函数返回时, 提前结束扫描且 Scan 不返回错误
+var ErrStopScan = errors.New("stop scan")
+
+// Scan 按 docid 升序分批遍历 query 匹配的全部文档, 对每个文档调用 fn
+//
+// batchSize 为每批读取的文档数, 小于 2 时使用 100. 每批都与上一批重叠一个文档,
+// 以 docid 判断位置: 扫描期间新增的文档排在末尾, 删除文档导致偏移量前移时会回退重读,
+// 因此已存在的文档既不会遗漏也不会重复. fn 返回 ErrStopScan 时结束扫描, 返回其它错误时中止并返回该错误.
+//
+// 扫描会将排序方式设为按 docid 排序, 结束后恢复为按相关性排序
+func (searcher *Searcher) Scan(query string, batchSize uint32, fn func(doc *schema.Document) error) error {
+	if batchSize < 2 {
+		batchSize = 100
+	}
+	if err := searcher.SetDocOrder(true); err != nil {
+		return err
+	}
+	defer searcher.keepLimit()()
+	err := searcher.scan(query, batchSize, fn)
+	if serr := searcher.SetSort("", false); err == nil {
+		err = serr
+	}
+	if err == ErrStopScan {
+		return nil
+	}
+	return err
+}
+
+func (searcher *Searcher) scan(query string, batchSize uint32, fn func(doc *schema.Document) error) error {
+	var (
+		offset  uint32
+		lastID  uint32
+		started bool
+	)
+	for {
+		docs, err := searcher.Limit(batchSize, offset).fetch(query)
+		if err != nil {
+			return err
+		}
+		// 第一个文档应是上一批的最后一个, 否则说明有文档被删除, 需回退
+		if started && offset > 0 && len(docs) > 0 && docs[0].Docid > lastID {
+			if offset > batchSize {
+				offset -= batchSize
+			} else {
+				offset = 0
+			}
+			continue
+		}
+		for _, doc := range docs {
+			if started && doc.Docid <= lastID {
+				continue
+			}
+			if err := fn(doc); err != nil {
+				return err
+			}
+			lastID = doc.Docid
+			started = true
+		}
+		if uint32(len(docs)) < batchSize {
+			return nil
+		}
+		offset += batchSize - 1
+	}
+}
@@ -13,6 +13,7 @@ type Config struct {
 	Name         string
 	IndexServer  string `toml:"index_server"`
 	SearchServer string `toml:"search_server"`
+	Home         string `toml:"home"`
 	Fields       map[string]Field
 }
 
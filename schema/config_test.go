@@ -0,0 +1,31 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestConfig_Home(t *testing.T) {
+	conf := `
+name = "demo"
+home = "/data/xunsearch/demo"
+
+[fields.id]
+type = "id"
+`
+	cfg := new(Config)
+	if _, err := toml.Decode(conf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	setting, err := cfg.checkValid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if setting.Conf.Home != "/data/xunsearch/demo" {
+		t.Errorf("home = %v, want = %v", setting.Conf.Home, "/data/xunsearch/demo")
+	}
+	if setting.Conf.IndexServer != "127.0.0.1:8383" {
+		t.Errorf("index server = %v, want = %v", setting.Conf.IndexServer, "127.0.0.1:8383")
+	}
+}
@@ -0,0 +1,230 @@
+package schema
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 以下标签名用于读取文档的搜索信息而非字段值, 如:
+//
+//	Rank int `xs:"@rank"`
+const (
+	TagDocid    = "@docid"
+	TagRank     = "@rank"
+	TagCcount   = "@ccount"
+	TagPercent  = "@percent"
+	TagWeight   = "@weight"
+	TagDistance = "@distance"
+	TagDB       = "@db"
+)
+
+// dateLayouts 日期字段可识别的格式, 纯数字且不是8位时视为 unix 时间戳
+var dateLayouts = []string{"20060102", "2006-01-02", "2006-01-02 15:04:05", time.RFC3339}
+
+type structField struct {
+	name      string
+	index     []int
+	omitempty bool
+}
+
+var structCache sync.Map
+
+// structFields 返回带 xs 标签的结构体字段, 支持嵌入的匿名结构体
+func structFields(t reflect.Type) []structField {
+	if fields, ok := structCache.Load(t); ok {
+		return fields.([]structField)
+	}
+	fields := appendStructFields([]structField{}, t, nil)
+	structCache.Store(t, fields)
+	return fields
+}
+
+func appendStructFields(fields []structField, t reflect.Type, index []int) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+		tag, ok := sf.Tag.Lookup("xs")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				fields = appendStructFields(fields, sf.Type, idx)
+			}
+			continue
+		}
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		field := structField{name: opts[0], index: idx}
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				field.omitempty = true
+			}
+		}
+		if field.name == "" {
+			field.name = strings.ToLower(sf.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Decode 将文档的字段按 xs 标签写入 v 指向的结构体
+//
+//	type Post struct {
+//		Id      string    `xs:"pid"`
+//		Price   float64   `xs:"price"`
+//		Created time.Time `xs:"created"`
+//		Rank    int       `xs:"@rank"`
+//	}
+//
+// 标签名为空时使用小写的字段名. 数值、布尔、time.Time、[]string(以空格分隔)
+// 和实现了 encoding.TextUnmarshaler 的类型会被自动转换, 文档中不存在的字段保持不变
+func (doc *Document) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode: non-nil pointer to struct expected, got %T", v)
+	}
+	rv = rv.Elem()
+	for _, f := range structFields(rv.Type()) {
+		value, ok := doc.value(f.name)
+		if !ok {
+			continue
+		}
+		if err := setValue(rv.FieldByIndex(f.index), value); err != nil {
+			return fmt.Errorf("decode field '%s': %v", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (doc *Document) value(name string) (string, bool) {
+	switch name {
+	case TagDocid:
+		return strconv.FormatUint(uint64(doc.Docid), 10), true
+	case TagRank:
+		return strconv.FormatUint(uint64(doc.Rank), 10), true
+	case TagCcount:
+		return strconv.FormatUint(uint64(doc.Ccount), 10), true
+	case TagPercent:
+		return strconv.FormatInt(int64(doc.Percent), 10), true
+	case TagWeight:
+		return strconv.FormatFloat(float64(doc.Weight), 'f', -1, 32), true
+	case TagDistance:
+		return strconv.FormatFloat(doc.Distance, 'f', -1, 64), true
+	case TagDB:
+		return doc.DB, true
+	}
+	value, ok := doc.Fields[name]
+	return value, ok
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s)
+	}
+	if _, ok := v.Interface().(time.Time); ok {
+		t, err := ParseDate(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	s = strings.TrimSpace(s)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			// 数值字段可能以浮点数形式保存, 如 "10.0"
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil || f != float64(int64(f)) || v.OverflowInt(int64(f)) {
+				return err
+			}
+			i = int64(f)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			f, ferr := strconv.ParseFloat(s, 64)
+			if ferr != nil || f < 0 || f != float64(uint64(f)) || v.OverflowUint(uint64(f)) {
+				return err
+			}
+			u = uint64(f)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		words := strings.Fields(s)
+		sv := reflect.MakeSlice(v.Type(), len(words), len(words))
+		for i, w := range words {
+			sv.Index(i).SetString(w)
+		}
+		v.Set(sv)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// ParseDate 解析日期字段的值, 支持 20060102、2006-01-02、2006-01-02 15:04:05、RFC3339 和 unix 时间戳
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if len(s) != 8 {
+		if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(ts, 0), nil
+		}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+}
@@ -0,0 +1,91 @@
+package schema
+
+import (
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	Id string `xs:"pid"`
+}
+
+type testPost struct {
+	testBase
+	Title   string    `xs:"subject"`
+	Price   float64   `xs:"price"`
+	Num     int       `xs:"num"`
+	Hits    *uint32   `xs:"hits"`
+	Online  bool      `xs:"online"`
+	Tags    []string  `xs:"tags"`
+	Created time.Time `xs:"created"`
+	Rank    int       `xs:"@rank"`
+	Percent int32     `xs:"@percent"`
+	Weight  float32   `xs:"@weight"`
+	Ignored string    `xs:"-"`
+}
+
+func TestDocument_Decode(t *testing.T) {
+	doc := &Document{
+		Fields: map[string]string{
+			"pid":     "p1",
+			"subject": "杭州西湖",
+			"price":   "12.5",
+			"num":     "3.0",
+			"hits":    "42",
+			"online":  "1",
+			"tags":    "a b  c",
+			"created": "20200102",
+			"-":       "x",
+		},
+		Rank:    2,
+		Percent: 88,
+		Weight:  1.5,
+	}
+	var post testPost
+	if err := doc.Decode(&post); err != nil {
+		t.Fatal(err)
+	}
+	if post.Id != "p1" || post.Title != "杭州西湖" || post.Price != 12.5 || post.Num != 3 || !post.Online {
+		t.Errorf("unexpected fields %+v", post)
+	}
+	if post.Hits == nil || *post.Hits != 42 {
+		t.Errorf("hits = %v", post.Hits)
+	}
+	if len(post.Tags) != 3 || post.Tags[2] != "c" {
+		t.Errorf("tags = %v", post.Tags)
+	}
+	if !post.Created.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("created = %v", post.Created)
+	}
+	if post.Rank != 2 || post.Percent != 88 || post.Weight != 1.5 || post.Ignored != "" {
+		t.Errorf("unexpected meta %+v", post)
+	}
+	if err := doc.Decode(post); err == nil {
+		t.Error("decode into non-pointer should fail")
+	}
+	doc.Fields["num"] = "abc"
+	if err := doc.Decode(&post); err == nil {
+		t.Error("decode invalid number should fail")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"20200102", time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)},
+		{"2020-01-02 03:04:05", time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)},
+		{"1577934245", time.Unix(1577934245, 0)},
+		{"", time.Time{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%v) = %v, %v, want = %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := ParseDate("2020/01/02"); err == nil {
+		t.Error("invalid date should fail")
+	}
+}
@@ -18,6 +18,21 @@ type Document struct {
 	Percent int32
 	Weight  float32
 	Matched []string
+	// Distance 到地理位置排序基点的距离(米), 仅在按 geo 距离排序时有效, -1 表示无法计算
+	Distance float64
+	// DB 文档所在的数据库, 同时搜索多个数据库时用于区分来源
+	DB string
+	// 索引时附加的索引词和索引文本
+	terms   map[string]schemaTerm
+	indexes map[string]string
+}
+
+// NewIndexDocument creates document to be indexed with fields
+func NewIndexDocument(fields map[string]string) *Document {
+	if fields == nil {
+		fields = make(map[string]string)
+	}
+	return &Document{Fields: fields}
 }
 
 // NewDocument creates document instance from meta
@@ -39,3 +54,69 @@ func NewDocument(meta string) (*Document, error) {
 	}
 	return doc, nil
 }
+
+// CollapseCount 返回与该文档折叠在一起的其它文档数量
+//
+// 仅在调用 Searcher.SetCollapse 后的搜索结果中有效
+func (doc *Document) CollapseCount() uint32 {
+	return doc.Ccount
+}
+
+// AddTerm 为字段附加一个不在字段值中出现的索引词, 同一个词多次添加时权重累加
+//
+// 可用于给文档注入隐藏的关键词, weight 为 0 时按 1 处理
+func (doc *Document) AddTerm(field, term string, weight uint8) {
+	if weight == 0 {
+		weight = 1
+	}
+	if doc.terms == nil {
+		doc.terms = make(map[string]schemaTerm)
+	}
+	m, ok := doc.terms[field]
+	if !ok {
+		m = make(schemaTerm)
+		doc.terms[field] = m
+	}
+	m[term] += weight
+}
+
+// AddIndex 为字段附加一段只用于索引而不保存的文本, 文本由服务端分词
+func (doc *Document) AddIndex(field, text string) {
+	if text == "" {
+		return
+	}
+	if doc.indexes == nil {
+		doc.indexes = make(map[string]string)
+	}
+	if m, ok := doc.indexes[field]; ok {
+		doc.indexes[field] = m + "\n" + text
+	} else {
+		doc.indexes[field] = text
+	}
+}
+
+// GetAddTerms 返回字段附加的索引词及其权重
+func (doc *Document) GetAddTerms(field string) (map[string]uint8, bool) {
+	terms, ok := doc.terms[field]
+	return terms, ok
+}
+
+// GetAddIndex 返回字段附加的索引文本
+func (doc *Document) GetAddIndex(field string) (string, bool) {
+	text, ok := doc.indexes[field]
+	return text, ok
+}
+
+// AddedFields 返回附加了索引词或索引文本的字段
+func (doc *Document) AddedFields() []string {
+	fields := []string{}
+	for f := range doc.terms {
+		fields = append(fields, f)
+	}
+	for f := range doc.indexes {
+		if _, ok := doc.terms[f]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
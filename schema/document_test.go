@@ -0,0 +1,30 @@
+package schema
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestDocument_AddTerm(t *testing.T) {
+	doc := NewIndexDocument(nil)
+	doc.AddTerm("title", "杭州", 0)
+	doc.AddTerm("title", "杭州", 2)
+	doc.AddIndex("body", "西湖")
+	doc.AddIndex("body", "")
+	doc.AddIndex("body", "断桥")
+	terms, ok := doc.GetAddTerms("title")
+	if !ok || terms["杭州"] != 3 {
+		t.Errorf("GetAddTerms() = %v, %v", terms, ok)
+	}
+	if text, ok := doc.GetAddIndex("body"); !ok || text != "西湖\n断桥" {
+		t.Errorf("GetAddIndex() = %v, %v", text, ok)
+	}
+	if _, ok := doc.GetAddTerms("body"); ok {
+		t.Error("body has no added terms")
+	}
+	fields := doc.AddedFields()
+	sort.Strings(fields)
+	if len(fields) != 2 || fields[0] != "body" || fields[1] != "title" {
+		t.Errorf("AddedFields() = %v", fields)
+	}
+}
@@ -0,0 +1,137 @@
+package schema
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CheckStruct 检查结构体类型 t 的 xs 标签: 每个标签都须是已定义的字段(或 @rank 等搜索信息),
+// 且必须包含主键字段
+func (sc *Schema) CheckStruct(t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("struct expected, got %s", t)
+	}
+	hasID := false
+	for _, f := range structFields(t) {
+		if isMetaTag(f.name) {
+			continue
+		}
+		if strings.HasPrefix(f.name, "@") {
+			return fmt.Errorf("unknown tag '%s' of %s", f.name, t)
+		}
+		if _, ok := sc.FieldMetas[f.name]; !ok {
+			return fmt.Errorf("field '%s' of %s is not defined", f.name, t)
+		}
+		if f.name == sc.StrId {
+			hasID = true
+		}
+	}
+	if !hasID {
+		return fmt.Errorf("missing id field '%s' in %s", sc.StrId, t)
+	}
+	return nil
+}
+
+// Encode 将带 xs 标签的结构体转换为索引用的文档, v 为结构体或其指针
+//
+// 数值按十进制格式化, 布尔值为 1/0, 切片的元素以空格连接. time.Time 在 date 字段中格式化为 20060102,
+// 在 numeric 字段中为 unix 时间戳, 其它字段为 RFC3339. nil 指针和带 omitempty 选项的零值会被忽略
+func (sc *Schema) Encode(v interface{}) (map[string]string, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("encode: nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("encode: struct expected, got %T", v)
+	}
+	doc := make(map[string]string)
+	for _, f := range structFields(rv.Type()) {
+		meta, ok := sc.FieldMetas[f.name]
+		if !ok {
+			continue
+		}
+		fv := rv.FieldByIndex(f.index)
+		if f.omitempty && fv.IsZero() {
+			continue
+		}
+		value, ok, err := formatValue(fv, meta)
+		if err != nil {
+			return nil, fmt.Errorf("encode field '%s': %v", f.name, err)
+		}
+		if ok {
+			doc[f.name] = value
+		}
+	}
+	return doc, nil
+}
+
+func isMetaTag(name string) bool {
+	switch name {
+	case TagDocid, TagRank, TagCcount, TagPercent, TagWeight, TagDistance, TagDB:
+		return true
+	}
+	return false
+}
+
+// formatValue 格式化字段值, nil 指针返回 false
+func formatValue(v reflect.Value, meta *FieldMeta) (string, bool, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false, nil
+		}
+		return formatValue(v.Elem(), meta)
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return "", true, nil
+		}
+		if meta.IsDate() {
+			return t.Format("20060102"), true, nil
+		} else if meta.IsNumeric() {
+			return strconv.FormatInt(t.Unix(), 10), true, nil
+		}
+		return t.Format(time.RFC3339), true, nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err == nil, err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", true, nil
+		}
+		return "0", true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), true, nil
+	case reflect.Slice, reflect.Array:
+		values := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			value, ok, err := formatValue(v.Index(i), meta)
+			if err != nil {
+				return "", false, err
+			}
+			if ok && value != "" {
+				values = append(values, value)
+			}
+		}
+		return strings.Join(values, " "), true, nil
+	}
+	return "", false, fmt.Errorf("unsupported type %s", v.Type())
+}
@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestSchema(t *testing.T) *Schema {
+	sc, err := newSchema(map[string]Field{
+		"pid":     {Type: "id"},
+		"subject": {Type: "title"},
+		"price":   {Type: "numeric"},
+		"num":     {Type: "numeric"},
+		"hits":    {Type: "numeric"},
+		"online":  {Type: "string"},
+		"tags":    {Type: "string"},
+		"created": {Type: "date"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func TestSchema_CheckStruct(t *testing.T) {
+	sc := newTestSchema(t)
+	if err := sc.CheckStruct(reflect.TypeOf(&testPost{})); err != nil {
+		t.Error(err)
+	}
+	type typo struct {
+		Id    string `xs:"pid"`
+		Title string `xs:"subjcet"`
+	}
+	if err := sc.CheckStruct(reflect.TypeOf(typo{})); err == nil {
+		t.Error("undefined field should fail")
+	}
+	type noID struct {
+		Title string `xs:"subject"`
+	}
+	if err := sc.CheckStruct(reflect.TypeOf(noID{})); err == nil {
+		t.Error("missing id should fail")
+	}
+	type badMeta struct {
+		Id   string `xs:"pid"`
+		Rank int    `xs:"@rnak"`
+	}
+	if err := sc.CheckStruct(reflect.TypeOf(badMeta{})); err == nil {
+		t.Error("unknown meta tag should fail")
+	}
+}
+
+func TestSchema_Encode(t *testing.T) {
+	sc := newTestSchema(t)
+	post := &testPost{
+		testBase: testBase{Id: "p1"},
+		Title:    "杭州西湖",
+		Price:    12.5,
+		Num:      3,
+		Online:   true,
+		Tags:     []string{"a", "b"},
+		Created:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local),
+		Rank:     9,
+	}
+	doc, err := sc.Encode(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"pid":     "p1",
+		"subject": "杭州西湖",
+		"price":   "12.5",
+		"num":     "3",
+		"online":  "1",
+		"tags":    "a b",
+		"created": "20200102",
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("Encode() = %v, want = %v", doc, want)
+	}
+	if _, err := sc.Encode("p1"); err == nil {
+		t.Error("encode non-struct should fail")
+	}
+}
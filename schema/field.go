@@ -42,6 +42,10 @@ func (meta *FieldMeta) IsNumeric() bool {
 	return meta.Type == "numeric"
 }
 
+func (meta *FieldMeta) IsDate() bool {
+	return meta.Type == "date"
+}
+
 func (meta *FieldMeta) IsSpecial() bool {
 	return meta.Type == "id" || meta.Type == "title" || meta.Type == "body"
 }
@@ -84,6 +84,8 @@ func newSchema(fields map[string]Field) (*Schema, error) {
 }
 
 // AddTerm to a field of the Schema
+//
+// Deprecated: 附加的索引词属于文档, 请使用 Document.AddTerm
 func (sc *Schema) AddTerm(field, term string, weight uint8) {
 	if weight == 0 {
 		weight = 1
@@ -102,6 +104,8 @@ func (sc *Schema) AddTerm(field, term string, weight uint8) {
 }
 
 // AddIndex to a field of the Schema
+//
+// Deprecated: 附加的索引文本属于文档, 请使用 Document.AddIndex
 func (sc *Schema) AddIndex(field, index string) {
 	if index == "" {
 		return
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ninggf/xs4go/cmd"
 	"github.com/ninggf/xs4go/schema"
@@ -16,11 +17,21 @@ import (
 
 const (
 	logDB string = "log_db"
+	// defaultParseFlags 服务端默认的搜索语句解析标志
+	defaultParseFlags uint16 = cmd.XS_CMD_PARSE_FLAG_BOOLEAN | cmd.XS_CMD_PARSE_FLAG_PHRASE | cmd.XS_CMD_PARSE_FLAG_LOVEHATE
+	// parseFlagMask 全部可用的解析标志
+	parseFlagMask uint16 = 0x7ff
 )
 
 // Facet for document filed
 type Facet map[string]int32
 
+// SortField 多字段排序中的一个排序字段
+type SortField struct {
+	Name string
+	Asc  bool
+}
+
 // Searcher indicates a index server
 type Searcher struct {
 	Facets      map[string]Facet
@@ -38,11 +49,17 @@ type Searcher struct {
 	limit       uint32
 	offset      uint32
 	curDB       string
-	curDBs      map[string]bool
+	curDBs      []string
 	lastDB      string
+	lastDBs     []string
 	lastHlQuery string
+	hlTerms     []string
 	query       string
 	terms       []string
+	geo         *geoSort
+	parseFlags  uint16
+	correction  uint32
+	rerun       bool
 }
 
 // NewSearcher creates a searcher that connect to search server
@@ -66,6 +83,13 @@ func NewSearcher(conf string) (*Searcher, error) {
 	return searcher.setProject(setting.Conf.Name)
 }
 
+// SetTokenizer sets the tokenizer used to split the value of field query
+func (searcher *Searcher) SetTokenizer(tokenizer tokenizer.Tokenizer) {
+	if tokenizer != nil {
+		searcher.tokenizer = tokenizer
+	}
+}
+
 // Fuzzy mode
 func (searcher *Searcher) Fuzzy(fuzzy bool) {
 	if fuzzy {
@@ -125,17 +149,172 @@ func (searcher *Searcher) SetWeightingScheme(policy uint8) *Searcher {
 	return searcher
 }
 
+// SetSort 设置单字段排序方式
+//
+// field 为空时恢复按相关性排序, asc 为 true 时按字段值升序排列
+func (searcher *Searcher) SetSort(field string, asc bool) error {
+	if field == "" {
+		searcher.geo = nil
+		cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_SORT, cmd.XS_CMD_SORT_TYPE_RELEVANCE, 0)
+		_, err := searcher.conn.ExecOK(cmdx, 0)
+		return err
+	}
+	f, err := searcher.getField(field)
+	if err != nil {
+		return err
+	}
+	searcher.geo = nil
+	sortType := uint8(cmd.XS_CMD_SORT_TYPE_VALUE)
+	if asc {
+		sortType |= cmd.XS_CMD_SORT_FLAG_ASCENDING
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_SORT, sortType, f.Vno)
+	_, err = searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
+// SetDocOrder 按文档入库顺序(docid)排序, asc 为 true 时先入库的文档在前
+func (searcher *Searcher) SetDocOrder(asc bool) error {
+	searcher.geo = nil
+	sortType := uint8(cmd.XS_CMD_SORT_TYPE_DOCID)
+	if asc {
+		sortType |= cmd.XS_CMD_SORT_FLAG_ASCENDING
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_SORT, sortType, 0)
+	_, err := searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
+// SetMultiSort 设置多字段组合排序方式
+//
+// 按 fields 的顺序依次比较各字段的值, relevanceFirst 为 true 时优先按相关性排序,
+// 相关性相同的文档再按字段排序. 混合区字段(body)无法用于排序, 将被忽略
+func (searcher *Searcher) SetMultiSort(fields []SortField, relevanceFirst bool) error {
+	buf := bytes.NewBuffer([]byte{})
+	for _, sf := range fields {
+		f, err := searcher.getField(sf.Name)
+		if err != nil {
+			return err
+		}
+		if f.Vno == schema.MIXED_VNO {
+			continue
+		}
+		buf.WriteByte(f.Vno)
+		if sf.Asc {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	searcher.geo = nil
+	sortType := uint8(cmd.XS_CMD_SORT_TYPE_MULTI | cmd.XS_CMD_SORT_FLAG_ASCENDING)
+	if relevanceFirst {
+		sortType |= cmd.XS_CMD_SORT_FLAG_RELEVANCE
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_SORT, sortType, 0, buf.String())
+	_, err := searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
+// SetCollapse 按字段值折叠搜索结果, 相同值的文档最多保留 num 条
+//
+// field 为空时取消折叠. 被折叠掉的文档数量可通过结果文档的 CollapseCount 获取
+func (searcher *Searcher) SetCollapse(field string, num uint8) error {
+	vno := uint8(schema.MIXED_VNO)
+	if field != "" {
+		f, err := searcher.getField(field)
+		if err != nil {
+			return err
+		}
+		vno = f.Vno
+	}
+	if num == 0 {
+		num = 1
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_COLLAPSE, num, vno)
+	_, err := searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
+// SetFacets 设置分面搜索, 搜索时同时统计各字段值的匹配文档数
+//
+// fields 只能是 string 类型的字段, exact 为 true 时精确统计, 否则为估算值(速度更快).
+// 统计结果在 Search 之后通过 GetFacets 获取
+func (searcher *Searcher) SetFacets(fields []string, exact bool) error {
+	buf := bytes.NewBuffer([]byte{})
+	for _, name := range fields {
+		f, err := searcher.getField(name)
+		if err != nil {
+			return err
+		}
+		if f.Type != "" && f.Type != "string" {
+			return fmt.Errorf("field '%s' cannot be used for facets search, only string type is allowed", name)
+		}
+		buf.WriteByte(f.Vno)
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SET_FACETS, 0, 0, buf.String())
+	if exact {
+		cmdx.Arg1 = 1
+	}
+	_, err := searcher.conn.ExecOK(cmdx, 0)
+	return err
+}
+
+// GetFacets 获取最近一次搜索中指定字段的分面统计结果, 键为字段值, 值为匹配文档数
+func (searcher *Searcher) GetFacets(field string) Facet {
+	if facet, ok := searcher.Facets[field]; ok {
+		return facet
+	}
+	return Facet{}
+}
+
 // SetAutoSynonyms 开启自动同义词搜索功能
+//
+// 只改变同义词相关的解析标志, 其它标志保持不变
 func (searcher *Searcher) SetAutoSynonyms(auto bool) *Searcher {
-	flag := cmd.XS_CMD_PARSE_FLAG_BOOLEAN | cmd.XS_CMD_PARSE_FLAG_PHRASE | cmd.XS_CMD_PARSE_FLAG_LOVEHATE
-	if auto {
-		flag |= cmd.XS_CMD_PARSE_FLAG_AUTO_MULTIWORD_SYNONYMS
-	}
-	cmdx := cmd.NewCommand(cmd.XS_CMD_QUERY_PARSEFLAG, uint16(flag))
-	searcher.conn.ExecOK(cmdx, 0)
+	searcher.EnableParseFlag(cmd.XS_CMD_PARSE_FLAG_AUTO_MULTIWORD_SYNONYMS, auto)
 	return searcher
 }
 
+// SetParseFlags 设置搜索语句的解析标志, 为 XS_CMD_PARSE_FLAG_* 的组合, 如:
+//
+//	XS_CMD_PARSE_FLAG_BOOLEAN | XS_CMD_PARSE_FLAG_PHRASE | XS_CMD_PARSE_FLAG_PARTIAL
+//
+// 默认为 BOOLEAN | PHRASE | LOVEHATE
+func (searcher *Searcher) SetParseFlags(flags uint16) error {
+	if flags&^parseFlagMask != 0 {
+		return fmt.Errorf("invalid parse flags 0x%x", flags)
+	}
+	cmdx := cmd.NewCommand(cmd.XS_CMD_QUERY_PARSEFLAG, flags)
+	if _, err := searcher.conn.ExecOK(cmdx, 0); err != nil {
+		return err
+	}
+	searcher.parseFlags = flags
+	return nil
+}
+
+// EnableParseFlag 开启或关闭解析标志 flag, 不影响其它已设置的标志
+//
+// 如开启 XS_CMD_PARSE_FLAG_PARTIAL 后, 最后一个词按前缀匹配, 可用于输入提示;
+// 开启 XS_CMD_PARSE_FLAG_WILDCARD 后可使用 xi* 形式的通配搜索
+func (searcher *Searcher) EnableParseFlag(flag uint16, enable bool) error {
+	flags := searcher.parseFlags
+	if enable {
+		flags |= flag
+	} else {
+		flags &^= flag
+	}
+	return searcher.SetParseFlags(flags)
+}
+
+// GetParseFlags 返回当前的解析标志
+func (searcher *Searcher) GetParseFlags() uint16 {
+	return searcher.parseFlags
+}
+
 // SetSynonymScale 设置同义词搜索的权重比例 取值范围 0.01-2.55, 1 表示不调整
 func (searcher *Searcher) SetSynonymScale(scale float32) *Searcher {
 	if scale < 0.01 {
@@ -194,23 +373,8 @@ func (searcher *Searcher) GetSynonyms(word string) []string {
 //	searcher.Search 的语句一样, 请改用 {@link GetLastCount} 以提升效率
 // 最大长度为 80 字节
 func (searcher *Searcher) Count(query string) uint32 {
-	if query != "" {
-		query = searcher.preQueryString(query)
-	}
-	if query == "" && searcher.count != math.MaxUint32 {
-		return searcher.count
-	}
-	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_GET_TOTAL, 0, searcher.defaultOp, query)
-	if res, err := searcher.conn.ExecOK(cmdx, cmd.XS_CMD_OK_SEARCH_TOTAL); err == nil {
-		if rtn, err1 := cmd.UnPack("Icnt", res.Buf); err1 == nil {
-			cnt := rtn["cnt"].(uint32)
-			if query == "" {
-				searcher.count = cnt
-			}
-			return cnt
-		}
-	}
-	return 0
+	cnt, _ := searcher.getTotal(query)
+	return cnt
 }
 
 // GetLastCount 获取最近那次搜索的匹配总数估值
@@ -219,6 +383,8 @@ func (searcher *Searcher) GetLastCount() uint32 {
 }
 
 // Limit the result set
+//
+// 设置的数量和偏移量在再次调用 Limit 之前对之后的每次搜索都有效
 func (searcher *Searcher) Limit(limit ...uint32) *Searcher {
 	if len(limit) > 0 {
 		searcher.limit = limit[0]
@@ -234,98 +400,37 @@ func (searcher *Searcher) Limit(limit ...uint32) *Searcher {
 }
 
 // Search return results
-func (searcher *Searcher) Search(queries ...string) ([]*schema.Document, error) {
+//
+// 多个搜索语句以 AND 连接, 不指定时使用 SetQuery 等设置的默认搜索语句.
+// 结果数量少于 SetAutoCorrect 设置的阈值时会附带纠错建议
+func (searcher *Searcher) Search(queries ...string) (*SearchResult, error) {
+	start := time.Now()
 	query := strings.Join(queries, " AND ")
-	if query != "" {
-		query = searcher.preQueryString(query)
+	result, err := searcher.search(query)
+	if err != nil {
+		return nil, err
 	}
-	if searcher.limit == 0 {
-		searcher.limit = 10
+	if searcher.curDB != logDB {
+		if err := searcher.correct(result, query != ""); err != nil {
+			return nil, err
+		}
 	}
-	page, _ := cmd.Pack("II", searcher.offset, searcher.limit)
-	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_GET_RESULT, 0, searcher.defaultOp, query, page)
-	res, err := searcher.conn.ExecOK(cmdx, cmd.XS_CMD_OK_RESULT_BEGIN)
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+func (searcher *Searcher) search(query string) (*SearchResult, error) {
+	docs, err := searcher.fetch(query)
 	if err != nil {
-		return []*schema.Document{}, err
-	}
-	resx, err1 := cmd.UnPack("Icount", res.Buf)
-	if err1 != nil {
-		return []*schema.Document{}, err1
-	}
-	searcher.lastCount = resx["count"].(uint32)
-	searcher.limit = 10
-	searcher.offset = 0
-	var currSchema *schema.Schema
-	result := make([]*schema.Document, searcher.lastCount)
-	if searcher.curDB == logDB {
-		currSchema = searcher.setting.Logger
-	} else {
-		currSchema = searcher.setting.Schema
+		return nil, err
 	}
-	vnomap := currSchema.VnoMap()
-
-	var (
-		doc    *schema.Document
-		docIdx uint32
-		vno    uint8
-		vlen   uint32
-		num    int32
-	)
-
-	for {
-		mres, merr := searcher.conn.GetSearchResponse(res)
-		if merr != nil {
-			return []*schema.Document{}, merr
-		}
-		if mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_FACETS {
-			off := 0
-			ln := len(mres.Buf)
-			for (off + 6) < ln {
-				facts, err2 := cmd.UnPack("Cvno/Cvlen/Inum", mres.Buf[off:off+6])
-				if err2 != nil {
-					break
-				}
-				vno = facts["vno"].(uint8)
-				vlen = facts["vlen"].(uint32)
-				if fname, ok := vnomap[vno]; ok {
-					num = facts["num"].(int32)
-					value := mres.Buf[off+6 : off+6+int(vlen)]
-					facet, ok1 := searcher.Facets[fname]
-					if !ok1 {
-						facet = Facet{}
-					}
-					facet[value] = num
-				}
-				off += int(vlen) + 6
-			}
-		} else if mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_DOC {
-			doc, err = schema.NewDocument(mres.Buf)
-			if err != nil {
-				break
-			}
-			result[docIdx] = doc
-			docIdx++
-		} else if mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_FIELD {
-			if doc != nil {
-				fname, ok := vnomap[uint8(mres.GetArg())]
-				if !ok {
-					fname = strconv.Itoa(int(mres.GetArg()))
-				}
-				doc.Fields[fname] = mres.Buf
-			}
-		} else if mres.Cmd == cmd.XS_CMD_SEARCH_RESULT_MATCHED {
-			if doc != nil {
-				doc.Matched = strings.Split(" ", mres.Buf)
-			}
-		} else if mres.Cmd == cmd.XS_CMD_OK && cmd.XS_CMD_OK_RESULT_END == mres.GetArg() {
-			break
-		} else {
-			return []*schema.Document{}, fmt.Errorf("Unexpected respond in search :%v", mres)
+	result := &SearchResult{Docs: docs, Total: searcher.lastCount, Query: query}
+	result.Facets = searcher.Facets
+	if searcher.curDB != logDB {
+		if query == "" {
+			result.Query = searcher.query
 		}
-	}
-	if query == "" && searcher.curDB != logDB {
-		searcher.count = searcher.lastCount
-		searcher.logQuery()
+		result.Terms = searcher.Terms(query)
 	}
 	return result, nil
 }
@@ -352,7 +457,7 @@ func (searcher *Searcher) AddQueryString(query string, addOp uint8, scale float3
 	query = searcher.preQueryString(query)
 	bscale := ""
 	if scale > 0 && scale != 1 && scale < 655.35 {
-		if pd, err := cmd.Pack("n", int(scale*100)); err == nil {
+		if pd, err := cmd.Pack("n", uint16(scale*100)); err == nil {
 			bscale = pd
 		}
 	}
@@ -366,7 +471,7 @@ func (searcher *Searcher) AddQueryString(query string, addOp uint8, scale float3
 func (searcher *Searcher) AddQueryTerm(field string, addOp uint8, scale float32, terms ...string) {
 	bscale := ""
 	if scale > 0 && scale != 1 && scale < 655.35 {
-		if pd, err := cmd.Pack("n", int(scale*100)); err == nil {
+		if pd, err := cmd.Pack("n", uint16(scale*100)); err == nil {
 			bscale = pd
 		}
 	}
@@ -388,6 +493,20 @@ func (searcher *Searcher) AddQueryTerm(field string, addOp uint8, scale float32,
 	searcher.conn.ExecOK(cmdx, 0)
 }
 
+// AddWeight 增加附加条件权重词汇, 包含该词的文档将获得更高的排名, 但不影响匹配结果集
+//
+// field 为空时表示混合区词汇, scale 为权重缩放比例, 1 表示不缩放.
+// 与 SetQuery 组合使用, 对之后不带参数的 Search 生效
+func (searcher *Searcher) AddWeight(field, term string, scale float32) error {
+	if field != "" {
+		if _, err := searcher.getField(field); err != nil {
+			return err
+		}
+	}
+	searcher.AddQueryTerm(field, cmd.XS_CMD_QUERY_OP_AND_MAYBE, scale, strings.ToLower(term))
+	return nil
+}
+
 // GetQuery return a parased string
 func (searcher *Searcher) GetQuery(query string) (string, error) {
 	if query != "" {
@@ -398,12 +517,12 @@ func (searcher *Searcher) GetQuery(query string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	// TODO 处理 VALUE_RANGE VALUE_GE VALUE_LE
-
-	return res.Buf, nil
+	return searcher.formatValueRange(res.Buf), nil
 }
 
 // SetDB to search
+//
+// 之前通过 AddDB 添加的数据库将被清除
 func (searcher *Searcher) SetDB(db string) error {
 	cmdx := cmd.XsCommand{}
 	cmdx.Cmd = cmd.XS_CMD_SEARCH_SET_DB
@@ -412,12 +531,20 @@ func (searcher *Searcher) SetDB(db string) error {
 	_, err := searcher.conn.ExecOK(&cmdx, cmd.XS_CMD_OK_DB_CHANGED)
 	if err == nil {
 		searcher.lastDB, searcher.curDB = searcher.curDB, db
+		searcher.lastDBs, searcher.curDBs = searcher.curDBs, nil
 	}
 	return err
 }
 
 // AddDB to search
+//
+// 已在搜索范围内的数据库会被忽略
 func (searcher *Searcher) AddDB(db string) error {
+	for _, name := range searcher.ListDBs() {
+		if name == db {
+			return nil
+		}
+	}
 	cmdx := cmd.XsCommand{}
 	cmdx.Cmd = cmd.XS_CMD_SEARCH_ADD_DB
 	cmdx.Buf = db
@@ -425,7 +552,7 @@ func (searcher *Searcher) AddDB(db string) error {
 	_, err := searcher.conn.ExecOK(&cmdx, cmd.XS_CMD_OK_DB_CHANGED)
 
 	if err == nil {
-		searcher.curDBs[db] = true
+		searcher.curDBs = append(searcher.curDBs, db)
 	}
 
 	return err
@@ -466,7 +593,10 @@ func (searcher *Searcher) Terms(query string) []string {
 		}
 		rterms = append(rterms, terms[i])
 	}
-	searcher.terms = rterms
+	// 只缓存默认搜索语句的词汇
+	if query == "" {
+		searcher.terms = rterms
+	}
 	return rterms
 }
 
@@ -529,9 +659,10 @@ func (searcher *Searcher) GetHotQuery(hotType string, limits ...uint8) map[strin
 	if searcher.SetDB(logDB) != nil {
 		return result
 	}
+	defer searcher.keepLimit()()
 	searcher.Limit(uint32(limit))
-	if docs, err := searcher.Search(hotType + ":1"); err == nil {
-		for _, doc := range docs {
+	if res, err := searcher.Search(hotType + ":1"); err == nil {
+		for _, doc := range res.Docs {
 			body := doc.Fields["body"]
 			if v, ok := doc.Fields[hotType]; ok {
 				if vv, err := strconv.Atoi(v); err == nil {
@@ -560,11 +691,12 @@ func (searcher *Searcher) GetRelatedQuery(query string, limits ...uint8) []strin
 	if searcher.SetDB(logDB) != nil {
 		return result
 	}
+	defer searcher.keepLimit()()
 	searcher.Limit(uint32(limit + 1))
 	searcher.Fuzzy(true)
-	docs, err := searcher.Search(query)
+	res, err := searcher.Search(query)
 	if err == nil {
-		for _, doc := range docs {
+		for _, doc := range res.Docs {
 			body := doc.Fields["body"]
 			if strings.Compare(query, body) == 0 {
 				continue
@@ -589,7 +721,7 @@ func (searcher *Searcher) Close() {
 }
 
 func (searcher *Searcher) setProject(project string) (*Searcher, error) {
-	cmdx := cmd.UseProjectCmd(project)
+	cmdx := cmd.UseProjectWithHomeCmd(project, searcher.cfg.Home)
 
 	_, err := searcher.conn.ExecOK(cmdx, cmd.XS_CMD_OK_PROJECT)
 
@@ -603,7 +735,7 @@ func (searcher *Searcher) setProject(project string) (*Searcher, error) {
 	searcher.tokenizer = tokenizer
 	searcher.queryPrefix = make(map[string]bool)
 	searcher.Facets = make(map[string]Facet)
-	searcher.curDBs = make(map[string]bool)
+	searcher.parseFlags = defaultParseFlags
 	searcher.initSpecialField()
 	return searcher, nil
 }
@@ -614,8 +746,9 @@ func (searcher *Searcher) clearQuery() {
 
 	searcher.conn.ExecOK(&cmdx, 0)
 	searcher.query = ""
-	searcher.count = 0
+	searcher.count = math.MaxUint32
 	searcher.terms = nil
+	searcher.hlTerms = nil
 }
 
 func (searcher *Searcher) logQuery() {
@@ -689,10 +822,49 @@ func (searcher *Searcher) cleanFieldQuery(query string) string {
 	return query
 }
 
+func (searcher *Searcher) getTotal(query string) (uint32, error) {
+	if query != "" {
+		query = searcher.preQueryString(query)
+	}
+	if query == "" && searcher.count != math.MaxUint32 {
+		return searcher.count, nil
+	}
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_GET_TOTAL, 0, searcher.defaultOp, query)
+	res, err := searcher.conn.ExecOK(cmdx, cmd.XS_CMD_OK_SEARCH_TOTAL)
+	if err != nil {
+		return 0, err
+	}
+	rtn, err := cmd.UnPack("Icnt", res.Buf)
+	if err != nil {
+		return 0, err
+	}
+	cnt := rtn["cnt"].(uint32)
+	if query == "" {
+		searcher.count = cnt
+	}
+	return cnt, nil
+}
+
+func (searcher *Searcher) getField(name string) (*schema.FieldMeta, error) {
+	f, ok := searcher.schema.FieldMetas[name]
+	if !ok {
+		return nil, fmt.Errorf("field '%s' is not defined", name)
+	}
+	return f, nil
+}
+
+// keepLimit 返回恢复当前 limit 和 offset 的函数
+func (searcher *Searcher) keepLimit() func() {
+	limit, offset := searcher.limit, searcher.offset
+	return func() {
+		searcher.limit, searcher.offset = limit, offset
+	}
+}
+
 func (searcher *Searcher) restoreDb() {
-	db := searcher.lastDB
+	db, dbs := searcher.lastDB, searcher.lastDBs
 	searcher.SetDB(db)
-	for d := range searcher.curDBs {
+	for _, d := range dbs {
 		searcher.AddDB(d)
 	}
 }
@@ -3,10 +3,13 @@ package server
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/ninggf/xs4go/cmd"
 )
@@ -137,37 +140,31 @@ func (connection *Connection) GetSearchResponse(pcmd *cmd.XsCommand) (*cmd.XsCom
 	return connection.getResponse()
 }
 
+// GetResponse reads the next response from server, used by those commands
+// responding more than once, e.g. XS_CMD_SEARCH_SCWS_GET
+func (connection *Connection) GetResponse() (*cmd.XsCommand, error) {
+	if connection.conn == nil {
+		return nil, errors.New("do not connect to server yet, please connect to server first")
+	}
+	return connection.getResponse()
+}
+
 func (connection *Connection) getResponse() (*cmd.XsCommand, error) {
 	reader := connection.reader
-	buffer := bytes.NewBuffer([]byte{})
 	head := make([]byte, 8)
-	len, err := reader.Read(head)
-	if len != 8 {
-		return nil, fmt.Errorf("no more data")
+	if _, err := io.ReadFull(reader, head); err != nil {
+		return nil, fmt.Errorf("read response error: %w", err)
 	}
-	buffer.Write(head[:])
 	len1, len2, err := cmd.DecodeHead(head)
 	if err != nil {
 		return nil, err
 	}
-	if len1 > 0 {
-		buf := make([]byte, len1)
-		len, err := reader.Read(buf)
-		if err != nil || uint32(len) != len1 {
-			return nil, fmt.Errorf("read data error")
-		}
-		buffer.Write(buf[:])
-	}
-	if len2 > 0 {
-		buf := make([]byte, len2)
-		len, err := reader.Read(buf)
-		if err != nil || uint8(len) != len2 {
-			return nil, fmt.Errorf("read data error")
-		}
-		buffer.Write(buf[:])
+	resp := make([]byte, 8+int(len1)+int(len2))
+	copy(resp, head)
+	if _, err := io.ReadFull(reader, resp[8:]); err != nil {
+		return nil, fmt.Errorf("read data error: %w", err)
 	}
 	// 解析返回
-	resp := buffer.Bytes()
 	rcmd := new(cmd.XsCommand)
 	err = rcmd.Decode(resp, connection.IsBigEndian)
 	if err != nil {
@@ -177,6 +174,61 @@ func (connection *Connection) getResponse() (*cmd.XsCommand, error) {
 	return rcmd, nil
 }
 
+// SetDeadline sets the read and write deadlines of the connection, zero value means no deadline
+func (connection *Connection) SetDeadline(t time.Time) error {
+	if connection.conn == nil {
+		return errors.New("do not connect to server yet, please connect to server first")
+	}
+	return connection.conn.SetDeadline(t)
+}
+
+// WithContext runs fn with the deadline of ctx applied to the connection,
+// blocking reads and writes in fn are aborted as soon as ctx is canceled.
+//
+// The connection is closed if fn is aborted, because the rest of the response
+// left on the wire can not be consumed any more.
+func (connection *Connection) WithContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+	conn := connection.conn
+	if conn == nil {
+		return errors.New("do not connect to server yet, please connect to server first")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			// a deadline in the past wakes up the blocking read or write
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	err := fn()
+	close(done)
+	<-stopped
+	if err != nil {
+		var netErr net.Error
+		if ctx.Err() != nil || (errors.As(err, &netErr) && netErr.Timeout()) {
+			connection.Close()
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return context.DeadlineExceeded
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	return err
+}
+
 // ExecSync run in concurrency
 func (connection *Connection) execSync() {
 	go func() {
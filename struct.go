@@ -0,0 +1,56 @@
+package xs4go
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/ninggf/xs4go/schema"
+)
+
+// RegisterStruct 检查结构体 v 的 xs 标签是否与配置中的字段一致, v 可以是结构体或其指针
+//
+// 每个标签都须对应已定义的字段, 且必须包含主键字段. 检查结果按类型缓存,
+// AddStruct 和 UpdateStruct 会自动检查未注册的类型, 提前注册可在启动时发现拼写错误
+func (indexer *Indexer) RegisterStruct(v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return fmt.Errorf("struct expected, got nil")
+	}
+	if err, ok := indexer.structs[t]; ok {
+		return err
+	}
+	err := indexer.schema.CheckStruct(t)
+	if indexer.structs == nil {
+		indexer.structs = make(map[reflect.Type]error)
+	}
+	indexer.structs[t] = err
+	return err
+}
+
+// AddStruct 将带 xs 标签的结构体添加到索引, 转换规则参见 schema.Schema.Encode
+func (indexer *Indexer) AddStruct(v interface{}) error {
+	doc, err := indexer.encodeStruct(v)
+	if err != nil {
+		return err
+	}
+	return indexer.update(schema.NewIndexDocument(doc), true)
+}
+
+// UpdateStruct 以带 xs 标签的结构体更新索引中主键相同的文档
+func (indexer *Indexer) UpdateStruct(v interface{}) error {
+	doc, err := indexer.encodeStruct(v)
+	if err != nil {
+		return err
+	}
+	return indexer.update(schema.NewIndexDocument(doc), false)
+}
+
+func (indexer *Indexer) encodeStruct(v interface{}) (map[string]string, error) {
+	if err := indexer.RegisterStruct(v); err != nil {
+		return nil, err
+	}
+	return indexer.schema.Encode(v)
+}
@@ -1,8 +1,11 @@
 package test
 
 import (
+	"context"
 	"io/ioutil"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/ninggf/xs4go/cmd"
 	"github.com/ninggf/xs4go/server"
@@ -51,3 +54,46 @@ func Test_setProject(t *testing.T) {
 
 	conn.Close()
 }
+
+func Test_ExecWithContext(t *testing.T) {
+	// a server accepting connections but never responding
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+	conn, err := server.NewConnection(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = conn.WithContext(ctx, func() error {
+		_, err := conn.ExecOK(cmd.NewCommand(cmd.XS_CMD_SEARCH_DB_TOTAL, 0), cmd.XS_CMD_OK_DB_TOTAL)
+		return err
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want = %v", err, context.DeadlineExceeded)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("request is not aborted in time")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := conn.WithContext(ctx, func() error { return nil }); err != context.Canceled {
+		t.Errorf("err = %v, want = %v", err, context.Canceled)
+	}
+}
@@ -1,10 +1,15 @@
 package test
 
 import (
+	"bytes"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	xs "github.com/ninggf/xs4go"
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/schema"
 )
 
 func newIndexer(t *testing.T) *xs.Indexer {
@@ -77,3 +82,192 @@ func TestIndexer_FlushLoggin(t *testing.T) {
 
 	index.Close()
 }
+
+func TestIndexer_AddStruct(t *testing.T) {
+	type message struct {
+		Id      int    `xs:"id"`
+		Message string `xs:"message"`
+		Percent int    `xs:"@percent"`
+	}
+	type typo struct {
+		Id      int    `xs:"id"`
+		Message string `xs:"mesage"`
+	}
+	index := newIndexer(t)
+	if err := index.RegisterStruct(typo{}); err == nil {
+		t.Error("undefined field should fail")
+	}
+	if err := index.AddStruct(&typo{Id: 1020}); err == nil {
+		t.Error("undefined field should fail")
+	}
+	if err := index.AddStruct(message{Id: 1020, Message: "中国 日本"}); err != nil {
+		t.Error(err)
+	}
+	if err := index.UpdateStruct(&message{Id: 1020, Message: "中国 日本 韩国"}); err != nil {
+		t.Error(err)
+	}
+	index.Close()
+}
+
+func TestIndexer_AddDocument(t *testing.T) {
+	index := newIndexer(t)
+	doc := schema.NewIndexDocument(map[string]string{"id": "1021", "message": "中国 日本"})
+	doc.AddTerm("message", "隐藏词", 2)
+	doc.AddIndex("message", "只索引不保存")
+	if err := index.AddDocument(doc); err != nil {
+		t.Error(err)
+	}
+	doc.AddTerm("mesage", "拼写错误", 1)
+	if err := index.UpdateDocument(doc); err == nil {
+		t.Error("undefined field should fail")
+	}
+	index.Close()
+}
+
+func TestBulkIndexer(t *testing.T) {
+	index := newIndexer(t)
+	failed := 0
+	bulk := index.NewBulkIndexer(xs.BulkOptions{
+		MaxDocs:  10,
+		Interval: time.Second,
+		OnError: func(err error, batch *xs.BulkBatch) {
+			failed += len(batch.IDs)
+		},
+	})
+	for i := 0; i < 25; i++ {
+		doc := map[string]string{"id": strconv.Itoa(2000 + i), "message": "中国 日本 批量"}
+		if err := bulk.Add(doc); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := bulk.Add(map[string]string{"message": "no id"}); err == nil {
+		t.Error("missing id should fail")
+	}
+	if err := bulk.Close(); err != nil {
+		t.Error(err)
+	}
+	stats := bulk.Stats()
+	if stats.Docs+stats.Failed != 25 || stats.Failed != uint64(failed) || stats.Batches != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if err := bulk.Add(map[string]string{"id": "2100"}); err == nil {
+		t.Error("add after close should fail")
+	}
+	index.Close()
+}
+
+func TestIndexer_Import(t *testing.T) {
+	index := newIndexer(t)
+	jsonl := `{"id": 3001, "message": "中国 日本"}
+
+{"id": "3002", "msg": ["导入", "测试"]}
+{"message": "no id"}
+{"id": 3003, "mesage": "typo"}
+not json
+`
+	n, err := index.Import(strings.NewReader(jsonl), xs.ImportJSONL, &xs.ImportOptions{
+		Mapping: map[string]string{"msg": "message"},
+	})
+	errs, ok := err.(xs.ImportErrors)
+	if !ok || len(errs) != 3 || errs[0].Line != 4 || errs[2].Line != 6 {
+		t.Errorf("unexpected errors %v", err)
+	}
+	if n != 2 {
+		t.Errorf("imported %v docs, want 2", n)
+	}
+
+	csv := "id,message,note\n3004,\"中国, 日本\",x\n3005\n"
+	n, err = index.Import(strings.NewReader(csv), xs.ImportCSV, &xs.ImportOptions{
+		Mapping: map[string]string{"note": "-"},
+	})
+	if errs, ok := err.(xs.ImportErrors); !ok || len(errs) != 1 || errs[0].Line != 3 {
+		t.Errorf("unexpected errors %v", err)
+	}
+	if n != 1 {
+		t.Errorf("imported %v docs, want 1", n)
+	}
+	if _, err := index.Import(strings.NewReader("id,nofield\n"), xs.ImportCSV, nil); err == nil {
+		t.Error("undefined column should fail")
+	}
+	// 行号为物理行, 引号内的换行不算作新记录
+	csv = "id,message\n3008,\"多行\n文本\"\n3009\n3010,\"a\"b\"\n"
+	_, err = index.Import(strings.NewReader(csv), xs.ImportCSV, nil)
+	if errs, ok := err.(xs.ImportErrors); !ok || len(errs) != 2 || errs[0].Line != 4 || errs[1].Line != 5 {
+		t.Errorf("unexpected csv errors %v", err)
+	}
+
+	var native bytes.Buffer
+	native.Write(cmd.NewCommand(cmd.XS_CMD_IMPORT_HEADER, 0).Encode(false))
+	native.Write(cmd.NewCommand2(cmd.XS_CMD_INDEX_REQUEST, cmd.XS_CMD_INDEX_REQUEST_ADD, 0).Encode(false))
+	native.Write(cmd.NewCommand2(cmd.XS_CMD_DOC_VALUE, 0, 0, "3006").Encode(false))
+	native.Write(cmd.NewCommand(cmd.XS_CMD_INDEX_SUBMIT, 0).Encode(false))
+	if n, err := index.Import(&native, xs.ImportNative, nil); err != nil || n != 1 {
+		t.Errorf("native import: n = %v, err = %v", n, err)
+	}
+	if _, err := index.Import(strings.NewReader("xx"), xs.ImportNative, nil); err == nil {
+		t.Error("invalid native data should fail")
+	}
+	native.Reset()
+	native.Write(cmd.NewCommand(cmd.XS_CMD_IMPORT_HEADER, 0).Encode(false))
+	native.Write(cmd.NewCommand2(cmd.XS_CMD_INDEX_REQUEST, cmd.XS_CMD_INDEX_REQUEST_ADD, 0).Encode(false))
+	native.Write(cmd.NewCommand2(cmd.XS_CMD_DOC_VALUE, 0, 0, "3007").Encode(false))
+	if n, err := index.Import(&native, xs.ImportNative, nil); err == nil || n != 0 {
+		t.Errorf("truncated document: n = %v, err = %v", n, err)
+	}
+	// 文档之外的指令单独提交, 不会并入下一个文档
+	native.Reset()
+	native.Write(cmd.NewCommand(cmd.XS_CMD_IMPORT_HEADER, 0).Encode(false))
+	native.Write(cmd.NewCommand(cmd.XS_CMD_INDEX_REMOVE, 0, "3006").Encode(false))
+	if n, err := index.Import(&native, xs.ImportNative, nil); err != nil || n != 0 {
+		t.Errorf("standalone command: n = %v, err = %v", n, err)
+	}
+	if _, err := index.Import(&native, xs.ImportNative, &xs.ImportOptions{Update: true}); err == nil {
+		t.Error("native import with Update should fail")
+	}
+	index.Close()
+}
+
+func TestParseDict(t *testing.T) {
+	dict := "# 自定义词典\n迅搜\t10.0\t8.5\tnz\n\n新词 2\n;注释\n"
+	entries, err := xs.ParseDict(strings.NewReader(dict))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries %v, want 2", len(entries))
+	}
+	if entries[0] != (xs.DictEntry{Word: "迅搜", TF: 10, IDF: 8.5, Attr: "nz"}) {
+		t.Errorf("unexpected entry %v", entries[0])
+	}
+	if entries[1].String() != "新词\t2\t1\tn" {
+		t.Errorf("unexpected entry %q", entries[1].String())
+	}
+	entry := xs.DictEntry{Word: "迅搜", TF: 12.345, IDF: 0.125, Attr: "nz"}
+	if parsed, _ := xs.ParseDict(strings.NewReader(entry.String())); len(parsed) != 1 || parsed[0] != entry {
+		t.Errorf("round trip %v != %v", parsed, entry)
+	}
+	if _, err := xs.ParseDict(strings.NewReader("坏词 abc")); err == nil {
+		t.Error("invalid TF should fail")
+	}
+}
+
+func TestIndexer_CustomDict(t *testing.T) {
+	index := newIndexer(t)
+	entries := []xs.DictEntry{{Word: "迅搜", TF: 10, IDF: 8.5, Attr: "nz"}}
+	if err := index.SetCustomDict(entries); err != nil {
+		t.Error(err)
+	}
+	dict, err := index.GetCustomDict()
+	if err != nil || len(dict) != 1 || dict[0].Word != "迅搜" {
+		t.Errorf("unexpected dict %v, %v", dict, err)
+	}
+	index.Close()
+}
+
+func TestIndexer_DeleteProject(t *testing.T) {
+	index := newIndexer(t)
+	if err := index.DeleteProject("not-this-project"); err == nil {
+		t.Error("DeleteProject with wrong confirmation should fail")
+	}
+	index.Close()
+}
@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	xs "github.com/ninggf/xs4go"
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/query"
+	"github.com/ninggf/xs4go/schema"
 )
 
 func newSearcher(t *testing.T) *xs.Searcher {
@@ -96,11 +99,186 @@ func TestSearcher_Count(t *testing.T) {
 func TestSearcher_Search(t *testing.T) {
 	searcher := newSearcher(t)
 	searcher.SetQuery("日本")
-	docs, err := searcher.Search()
+	res, err := searcher.Search()
 	if err != nil {
-		t.Errorf("db total %v != 1", err)
+		t.Fatalf("db total %v != 1", err)
+	}
+	t.Error(res.Docs[0])
+	searcher.Close()
+}
+
+func TestSearcher_SearchResult(t *testing.T) {
+	searcher := newSearcher(t)
+	res, err := searcher.Limit(2).Search("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Total != searcher.GetLastCount() {
+		t.Errorf("total %v != %v", res.Total, searcher.GetLastCount())
+	}
+	if len(res.Docs) > 2 || uint32(len(res.Docs)) > res.Total {
+		t.Errorf("got %v docs, total = %v", len(res.Docs), res.Total)
+	}
+	if res.Query != "日本" || len(res.Terms) == 0 {
+		t.Errorf("query = %v, terms = %v", res.Query, res.Terms)
+	}
+	// limit 不会在搜索后被重置
+	res1, err := searcher.Search("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res1.Docs) > 2 {
+		t.Errorf("limit was reset, got %v docs", len(res1.Docs))
+	}
+	searcher.Close()
+}
+
+func TestSearcher_Iterate(t *testing.T) {
+	searcher := newSearcher(t)
+	it, err := searcher.Limit(5).Iterate("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for it.Next() {
+		if it.Doc() == nil {
+			t.Error("nil document")
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Error(err)
+	}
+	if n > 5 || uint32(n) > it.Total() {
+		t.Errorf("got %v docs, total = %v", n, it.Total())
+	}
+	it, err = searcher.Iterate("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := it.Close(); err != nil {
+		t.Error(err)
+	}
+	if _, err := searcher.Search("日本"); err != nil {
+		t.Error(err)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_Scan(t *testing.T) {
+	searcher := newSearcher(t)
+	total := searcher.Count("日本")
+	var (
+		n    uint32
+		last uint32
+	)
+	err := searcher.Scan("日本", 2, func(doc *schema.Document) error {
+		if n > 0 && doc.Docid <= last {
+			t.Errorf("docid %v after %v", doc.Docid, last)
+		}
+		last = doc.Docid
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if n != total {
+		t.Errorf("scanned %v docs, count = %v", n, total)
+	}
+	n = 0
+	err = searcher.Scan("日本", 2, func(doc *schema.Document) error {
+		n++
+		return xs.ErrStopScan
+	})
+	if err != nil || n > 1 {
+		t.Errorf("stop scan: n = %v, err = %v", n, err)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_SearchInto(t *testing.T) {
+	type message struct {
+		Id      string `xs:"id"`
+		Message string `xs:"message"`
+		Percent int    `xs:"@percent"`
+	}
+	searcher := newSearcher(t)
+	var msgs []message
+	res, err := searcher.SearchInto(&msgs, "日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != len(res.Docs) {
+		t.Errorf("decoded %v of %v docs", len(msgs), len(res.Docs))
+	}
+	for i, msg := range msgs {
+		if msg.Id != res.Docs[i].Fields["id"] {
+			t.Errorf("id %v != %v", msg.Id, res.Docs[i].Fields["id"])
+		}
+	}
+	if _, err := searcher.SearchInto(msgs, "日本"); err == nil {
+		t.Error("non-pointer dst should fail")
+	}
+	searcher.Close()
+}
+
+func TestSearcher_ListDBs(t *testing.T) {
+	searcher := newSearcher(t)
+	if dbs := searcher.ListDBs(); len(dbs) != 1 || dbs[0] != "db" {
+		t.Errorf("ListDBs() = %v", dbs)
+	}
+	if _, err := searcher.GetDB(); err != nil {
+		t.Error(err)
+	}
+	if err := searcher.RemoveDB("db"); err == nil {
+		t.Error("removing the only db should fail")
+	}
+	if err := searcher.AddDB("db_a"); err != nil {
+		t.Fatal(err)
+	}
+	searcher.AddDB("db_a")
+	if dbs := searcher.ListDBs(); len(dbs) != 2 || dbs[1] != "db_a" {
+		t.Errorf("ListDBs() = %v", dbs)
+	}
+	res, err := searcher.Search("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range res.Docs {
+		if doc.DB != "db" && doc.DB != "db_a" {
+			t.Errorf("unexpected db %v of doc %v", doc.DB, doc.Docid)
+		}
+	}
+	if err := searcher.RemoveDB("db_a"); err != nil {
+		t.Error(err)
+	}
+	if dbs := searcher.ListDBs(); len(dbs) != 1 || dbs[0] != "db" {
+		t.Errorf("ListDBs() = %v", dbs)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_EnableParseFlag(t *testing.T) {
+	searcher := newSearcher(t)
+	base := searcher.GetParseFlags()
+	if err := searcher.EnableParseFlag(cmd.XS_CMD_PARSE_FLAG_PARTIAL, true); err != nil {
+		t.Fatal(err)
+	}
+	searcher.SetAutoSynonyms(true)
+	if flags := searcher.GetParseFlags(); flags&cmd.XS_CMD_PARSE_FLAG_PARTIAL == 0 || flags&base != base {
+		t.Errorf("flags = 0x%x", flags)
+	}
+	searcher.SetAutoSynonyms(false)
+	if err := searcher.EnableParseFlag(cmd.XS_CMD_PARSE_FLAG_PARTIAL, false); err != nil {
+		t.Error(err)
+	}
+	if flags := searcher.GetParseFlags(); flags != base {
+		t.Errorf("flags = 0x%x, want = 0x%x", flags, base)
+	}
+	if err := searcher.SetParseFlags(0x8000); err == nil {
+		t.Error("invalid flags should fail")
 	}
-	t.Error(docs[0])
 	searcher.Close()
 }
 
@@ -114,6 +292,60 @@ func TestSearcher_GetCorrectedQuery(t *testing.T) {
 	searcher.Close()
 }
 
+func TestSearcher_SearchTerms(t *testing.T) {
+	searcher := newSearcher(t)
+	searcher.SetQuery("中国")
+	res, err := searcher.Search("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Terms) != 1 || res.Terms[0] != "日本" {
+		t.Errorf("terms of explicit query = %v", res.Terms)
+	}
+	// 指定搜索语句的词汇不能影响默认搜索语句
+	if res, err = searcher.Search(); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Terms) != 1 || res.Terms[0] != "中国" {
+		t.Errorf("terms of default query = %v", res.Terms)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_SetAutoCorrect(t *testing.T) {
+	searcher := newSearcher(t)
+	res, err := searcher.Search("ribeng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Corrected) != 0 || res.Substituted {
+		t.Errorf("auto correct is disabled by default, got %v", res.Corrected)
+	}
+	searcher.SetAutoCorrect(10, true)
+	res, err = searcher.Search("ribeng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Query != "ribeng" {
+		t.Errorf("query = %v", res.Query)
+	}
+	if res.Substituted && (res.Suggestion == "" || res.Total != searcher.GetLastCount()) {
+		t.Errorf("suggestion = %v, total = %v", res.Suggestion, res.Total)
+	}
+	if !res.Substituted && res.Total != searcher.GetLastCount() {
+		t.Errorf("last count %v != %v", searcher.GetLastCount(), res.Total)
+	}
+	// 默认搜索语句不会以纠错建议重新搜索
+	searcher.SetQuery("ribeng")
+	if res, err = searcher.Search(); err != nil {
+		t.Fatal(err)
+	}
+	if res.Substituted {
+		t.Errorf("search with SetQuery should not be substituted")
+	}
+	searcher.Close()
+}
+
 func TestSearcher_GetHotQuery(t *testing.T) {
 	searcher := newSearcher(t)
 	searcher.GetHotQuery("total")
@@ -125,3 +357,164 @@ func TestSearcher_GetRelatedQuery(t *testing.T) {
 	searcher.GetRelatedQuery("日本")
 	searcher.Close()
 }
+
+func TestSearcher_SetSort(t *testing.T) {
+	searcher := newSearcher(t)
+	if err := searcher.SetSort("id", true); err != nil {
+		t.Error(err)
+	}
+	if err := searcher.SetSort("nofield", true); err == nil {
+		t.Error("SetSort on undefined field should fail")
+	}
+	err := searcher.SetMultiSort([]xs.SortField{{Name: "id", Asc: false}}, true)
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err := searcher.Search("日本"); err != nil {
+		t.Error(err)
+	}
+	searcher.SetSort("", false)
+	searcher.Close()
+}
+
+func TestGeodist(t *testing.T) {
+	d := xs.Geodist(30.2741, 120.1551, 31.2304, 121.4737)
+	if d < 160000 || d > 170000 {
+		t.Errorf("distance between Hangzhou and Shanghai %v is out of range", d)
+	}
+	if xs.Geodist(30, 120, 30, 120) != 0 {
+		t.Error("distance of same point should be 0")
+	}
+}
+
+func TestSearcher_SetGeodistSort(t *testing.T) {
+	searcher := newSearcher(t)
+	if err := searcher.SetGeodistSort("id", "message", 30.27, 120.15); err == nil {
+		t.Error("SetGeodistSort on non-numeric fields should fail")
+	}
+	if err := searcher.SetGeoRadius(1000); err == nil {
+		t.Error("SetGeoRadius without geo sort should fail")
+	}
+	searcher.Close()
+}
+
+func TestSearcher_SetCollapse(t *testing.T) {
+	searcher := newSearcher(t)
+	if err := searcher.SetCollapse("id", 1); err != nil {
+		t.Error(err)
+	}
+	res, err := searcher.Search("日本")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// id 字段的值唯一, 折叠后每个值只出现一次且没有被折叠掉的文档
+	seen := make(map[string]int)
+	for _, doc := range res.Docs {
+		id := doc.Fields["id"]
+		if seen[id]++; seen[id] > 1 {
+			t.Errorf("id %v appears %d times", id, seen[id])
+		}
+		if doc.CollapseCount() != 0 {
+			t.Errorf("id %v collapse count %v != 0", id, doc.CollapseCount())
+		}
+	}
+	searcher.SetCollapse("", 0)
+	searcher.Close()
+}
+
+func TestSearcher_SetFacets(t *testing.T) {
+	searcher := newSearcher(t)
+	if err := searcher.SetFacets([]string{"nofield"}, false); err == nil {
+		t.Error("SetFacets on undefined field should fail")
+	}
+	if err := searcher.SetFacets([]string{"id"}, true); err == nil {
+		t.Error("SetFacets on id field should fail")
+	}
+	if _, err := searcher.Search("日本"); err != nil {
+		t.Error(err)
+	}
+	if facet := searcher.GetFacets("message"); len(facet) != 0 {
+		t.Errorf("unexpected facets %v", facet)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_AddRange(t *testing.T) {
+	searcher := newSearcher(t)
+	if err := searcher.AddRange("nofield", "1", "2"); err == nil {
+		t.Error("AddRange on undefined field should fail")
+	}
+	searcher.SetQuery("日本")
+	if err := searcher.AddRange("id", "1000", ""); err != nil {
+		t.Error(err)
+	}
+	q, _ := searcher.GetQuery("")
+	if q != "Query((日本@1 FILTER id:[1000,~]))" {
+		t.Errorf("%v != Query((日本@1 FILTER id:[1000,~]))", q)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_AddWeight(t *testing.T) {
+	searcher := newSearcher(t)
+	if err := searcher.AddWeight("nofield", "日本", 1); err == nil {
+		t.Error("AddWeight on undefined field should fail")
+	}
+	searcher.SetQuery("日本")
+	if err := searcher.AddWeight("", "中国", 2); err != nil {
+		t.Error(err)
+	}
+	q, _ := searcher.GetQuery("")
+	if q != "Query((日本@1 AND_MAYBE 中国@1 * 2))" {
+		t.Errorf("%v != Query((日本@1 AND_MAYBE 中国@1 * 2))", q)
+	}
+	searcher.Close()
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		opts  *xs.HighlightOptions
+		want  string
+	}{
+		{"杭州西湖很美", []string{"西湖"}, nil, "杭州<em>西湖</em>很美"},
+		{"西溪湿地", []string{"西溪", "溪湿", "湿地"}, nil, "<em>西溪湿地</em>"},
+		{"Go is GOOD", []string{"go"}, &xs.HighlightOptions{Pre: "[", Post: "]"}, "[Go] is [GO]OD"},
+		{"<b>日本</b>", []string{"日本"}, &xs.HighlightOptions{Escape: true}, "&lt;b&gt;<em>日本</em>&lt;/b&gt;"},
+		{"没有匹配", []string{"日本"}, nil, "没有匹配"},
+		{"杭州西湖", []string{"西湖"}, &xs.HighlightOptions{Pre: "<b>", Post: "</b>"}, "杭州<b>西湖</b>"},
+		{"杭州西湖", []string{"杭州"}, &xs.HighlightOptions{Pre: "【", Post: "】"}, "【杭州】西湖"},
+	}
+	for _, tt := range tests {
+		if got := xs.HighlightTerms(tt.text, tt.terms, tt.opts); got != tt.want {
+			t.Errorf("HighlightTerms(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearcher_Highlight(t *testing.T) {
+	searcher := newSearcher(t)
+	searcher.Search("日本")
+	if hl := searcher.Highlight("中国 日本", nil); hl != "中国 <em>日本</em>" {
+		t.Errorf("%v != 中国 <em>日本</em>", hl)
+	}
+	// 第二次高亮使用缓存的高亮词
+	if hl := searcher.Highlight("日本 中国", nil); hl != "<em>日本</em> 中国" {
+		t.Errorf("%v != <em>日本</em> 中国", hl)
+	}
+	searcher.Close()
+}
+
+func TestSearcher_SetQueryNode(t *testing.T) {
+	searcher := newSearcher(t)
+	err := searcher.SetQueryNode(query.AndNot(query.Term("上海:(人民"), query.Field("id", query.Term("1018"))))
+	if err != nil {
+		t.Error(err)
+	}
+	q, _ := searcher.GetQuery("")
+	if q != "Query(((上海@1 AND 人民@2) AND_NOT A1018))" {
+		t.Errorf("%v != Query(((上海@1 AND 人民@2) AND_NOT A1018))", q)
+	}
+	searcher.Close()
+}
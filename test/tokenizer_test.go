@@ -0,0 +1,30 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ninggf/xs4go/tokenizer"
+)
+
+func TestServerTokenizer(t *testing.T) {
+	tk, err := tokenizer.NewServerTokenizer("127.0.0.1:8384", "demox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tk.Close()
+	tokens := tk.GetTokens("我是中国人")
+	if len(tokens) == 0 {
+		t.Error("no tokens returned")
+	}
+	if _, err := tk.GetVersion(); err != nil {
+		t.Error(err)
+	}
+	tops, err := tk.GetTops("中国人民 中国 中国", 2, "")
+	if err != nil || len(tops) == 0 || tops[0].Word != "中国" {
+		t.Errorf("unexpected tops %v, %v", tops, err)
+	}
+	tk.SetMulti(tokenizer.SCWS_MULTI_SHORT)
+	if ok, err := tk.HasWord("我是中国人", "n"); err != nil || !ok {
+		t.Errorf("HasWord = %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,168 @@
+package tokenizer
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+
+	"github.com/ninggf/xs4go/cmd"
+	"github.com/ninggf/xs4go/server"
+)
+
+// Word 服务端分词结果中的一个词
+type Word struct {
+	Word  string
+	Attr  string // 词性
+	Off   uint32 // 在原文中的偏移, 仅 GetResult 返回
+	Times uint32 // 出现次数, 仅 GetTops 返回
+}
+
+// ServerTokenizer 调用搜索服务端内置的 scws 分词, 分词结果与服务端完全一致, 且无需 cgo 及 libscws
+type ServerTokenizer struct {
+	conn    *server.Connection
+	setting map[uint8]*cmd.XsCommand
+	mux     sync.Mutex
+}
+
+// NewServerTokenizer 连接到搜索服务端(如 127.0.0.1:8384)创建分词器
+//
+// project 不为空时会先切换到该项目, 以便使用项目的自定义词典
+func NewServerTokenizer(addr string, project string) (*ServerTokenizer, error) {
+	conn, err := server.NewConnection(addr)
+	if err != nil {
+		return nil, err
+	}
+	if project != "" {
+		if _, err := conn.ExecOK(cmd.UseProjectCmd(project), cmd.XS_CMD_OK_PROJECT); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	tokenizer := &ServerTokenizer{conn: conn, setting: make(map[uint8]*cmd.XsCommand)}
+	tokenizer.SetIgnore(true)
+	return tokenizer, nil
+}
+
+// GetTokens return terms split by scws on search server
+func (tokenizer *ServerTokenizer) GetTokens(text string) []string {
+	words, err := tokenizer.GetResult(text)
+	if err != nil {
+		return []string{}
+	}
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		tokens = append(tokens, word.Word)
+	}
+	return tokens
+}
+
+// GetResult 获取分词结果
+func (tokenizer *ServerTokenizer) GetResult(text string) ([]Word, error) {
+	tokenizer.mux.Lock()
+	defer tokenizer.mux.Unlock()
+	words := []Word{}
+	if strings.Trim(text, "\n\r ") == "" {
+		return words, nil
+	}
+	tokenizer.applySetting()
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SCWS_GET, cmd.XS_CMD_SCWS_GET_RESULT, 0, text)
+	res, err := tokenizer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_SCWS_RESULT)
+	for err == nil && len(res.Buf) >= 8 {
+		words = append(words, Word{
+			Word: res.Buf[8:],
+			Attr: strings.TrimRight(res.Buf[4:8], "\x00"),
+			Off:  binary.LittleEndian.Uint32([]byte(res.Buf[0:4])),
+		})
+		res, err = tokenizer.conn.GetResponse()
+	}
+	return words, err
+}
+
+// GetTops 获取出现次数最多的 limit 个词
+//
+// xattr 为词性过滤条件, 多个词性用逗号分隔, 以 ~ 开头表示排除这些词性, 空表示不过滤
+func (tokenizer *ServerTokenizer) GetTops(text string, limit uint8, xattr string) ([]Word, error) {
+	tokenizer.mux.Lock()
+	defer tokenizer.mux.Unlock()
+	words := []Word{}
+	if limit == 0 {
+		limit = 10
+	}
+	tokenizer.applySetting()
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SCWS_GET, cmd.XS_CMD_SCWS_GET_TOPS, limit, text, xattr)
+	res, err := tokenizer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_SCWS_TOPS)
+	for err == nil && len(res.Buf) >= 8 {
+		words = append(words, Word{
+			Word:  res.Buf[8:],
+			Attr:  strings.TrimRight(res.Buf[4:8], "\x00"),
+			Times: binary.LittleEndian.Uint32([]byte(res.Buf[0:4])),
+		})
+		res, err = tokenizer.conn.GetResponse()
+	}
+	return words, err
+}
+
+// HasWord 判断文本中是否包含指定词性的词
+func (tokenizer *ServerTokenizer) HasWord(text, xattr string) (bool, error) {
+	tokenizer.mux.Lock()
+	defer tokenizer.mux.Unlock()
+	tokenizer.applySetting()
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SCWS_GET, cmd.XS_CMD_SCWS_HAS_WORD, 0, text, xattr)
+	res, err := tokenizer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_INFO)
+	if err != nil {
+		return false, err
+	}
+	return res.Buf == "OK", nil
+}
+
+// GetVersion 获取服务端 scws 的版本号
+func (tokenizer *ServerTokenizer) GetVersion() (string, error) {
+	tokenizer.mux.Lock()
+	defer tokenizer.mux.Unlock()
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SCWS_GET, cmd.XS_CMD_SCWS_GET_VERSION, 0)
+	res, err := tokenizer.conn.ExecOK(cmdx, cmd.XS_CMD_OK_INFO)
+	if err != nil {
+		return "", err
+	}
+	return res.Buf, nil
+}
+
+// SetIgnore 设定分词结果是否忽略所有的标点等特殊符号
+func (tokenizer *ServerTokenizer) SetIgnore(yes bool) {
+	tokenizer.set(cmd.XS_CMD_SCWS_SET_IGNORE, yes)
+}
+
+// SetMulti 设定复合分词方式, mode 为 SCWS_MULTI_* 的组合
+func (tokenizer *ServerTokenizer) SetMulti(mode int) {
+	tokenizer.mux.Lock()
+	defer tokenizer.mux.Unlock()
+	arg := uint8(((mode & SCWS_MULTI_MASK) >> 12) & 0x0f)
+	tokenizer.setting[cmd.XS_CMD_SCWS_SET_MULTI] = cmd.NewCommand2(cmd.XS_CMD_SEARCH_SCWS_SET, cmd.XS_CMD_SCWS_SET_MULTI, arg)
+}
+
+// SetDuality 设定是否将闲散文字自动以二字分词法聚合
+func (tokenizer *ServerTokenizer) SetDuality(yes bool) {
+	tokenizer.set(cmd.XS_CMD_SCWS_SET_DUALITY, yes)
+}
+
+// Close the connection to search server
+func (tokenizer *ServerTokenizer) Close() {
+	tokenizer.conn.Close()
+}
+
+func (tokenizer *ServerTokenizer) set(arg uint8, yes bool) {
+	tokenizer.mux.Lock()
+	defer tokenizer.mux.Unlock()
+	cmdx := cmd.NewCommand2(cmd.XS_CMD_SEARCH_SCWS_SET, arg, 0)
+	if yes {
+		cmdx.Arg2 = 1
+	}
+	tokenizer.setting[arg] = cmdx
+}
+
+// applySetting 服务端的 scws 设置不会保留, 每次分词前都需重新发送
+func (tokenizer *ServerTokenizer) applySetting() {
+	for _, cmdx := range tokenizer.setting {
+		tokenizer.conn.ExecOK(cmdx, 0)
+	}
+}
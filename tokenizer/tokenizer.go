@@ -2,6 +2,18 @@ package tokenizer
 
 import "strings"
 
+const (
+	SCWS_MULTI_NONE    int = 0x00000 // 无
+	SCWS_MULTI_SHORT   int = 0x01000 // 短词
+	SCWS_MULTI_DUALITY int = 0x02000 // 二元（将相邻的2个单字组合成一个词）
+	SCWS_MULTI_ZMAIN   int = 0x04000 // 重要单字
+	SCWS_MULTI_ZALL    int = 0x08000 // 全部单字
+	SCWS_MULTI_MASK    int = 0xff000
+	SCWS_XDICT_XDB     int = 1
+	SCWS_XDICT_MEM     int = 2
+	SCWS_XDICT_TXT     int = 4
+)
+
 // Tokenizer used by indexer and searcher
 type Tokenizer interface {
 	GetTokens(text string) []string